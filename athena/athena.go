@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/cognusion/go-humanity"
@@ -31,6 +32,8 @@ type MemoryGuard struct {
 
 	cancelled      chan bool
 	nokill         bool // true if the process should not be killed in overmemory cases
+	killgroup      bool // true if the process group should be killed, instead of just the process
+	paused         atomic.Bool
 	proc           *os.Process
 	lastPss        int64
 	statsFrequency time.Duration
@@ -55,6 +58,13 @@ func (m *MemoryGuard) SetNoKill() {
 	m.nokill = true
 }
 
+// SetKillGroup changes the default behavior of the MemoryGuard, to kill the entire
+// process group the process leads if it exceeds the specified limit. The process must
+// have been started with Setpgid for this to be meaningful.
+func (m *MemoryGuard) SetKillGroup() {
+	m.killgroup = true
+}
+
 // StatsFrequency updates the internal frequency to which statistics are emitted to
 // the debug logger. Default is 1 minute.
 func (m *MemoryGuard) StatsFrequency(freq time.Duration) {
@@ -74,6 +84,16 @@ func (m *MemoryGuard) PSS() int64 {
 	return pss
 }
 
+// Pause suspends Limit() checks, e.g. while the process is stopped, until Resume
+func (m *MemoryGuard) Pause() {
+	m.paused.Store(true)
+}
+
+// Resume restarts Limit() checks suspended by Pause
+func (m *MemoryGuard) Resume() {
+	m.paused.Store(false)
+}
+
 // Cancel stops any Limit() operations. After calling Cancel this
 // MemoryGuard will be non-functional
 func (m *MemoryGuard) Cancel() {
@@ -106,6 +126,11 @@ func (m *MemoryGuard) Limit(max int64) {
 			default:
 			}
 
+			if m.paused.Load() {
+				time.Sleep(m.Interval)
+				continue
+			}
+
 			var (
 				xss int64
 				err error
@@ -125,6 +150,9 @@ func (m *MemoryGuard) Limit(max int64) {
 				close(m.KillChan)
 				if m.nokill {
 					// don't kill it
+				} else if m.killgroup {
+					// kill it, and everything in its group
+					syscall.Kill(-m.proc.Pid, syscall.SIGKILL)
 				} else {
 					// kill it
 					m.proc.Kill()
@@ -1,7 +1,12 @@
 package athena
 
 import (
+	"bufio"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -134,3 +139,67 @@ func Test_MemoryGuardKillPSS(t *testing.T) {
 		})
 	})
 }
+
+func Test_MemoryGuardKillGroup(t *testing.T) {
+	Convey("When a MemoryGuard is running on a process group leader with a child", t, func() {
+		cmd := exec.Command("bash", "-c", "sleep 30 & echo $!; wait")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		stdout, err := cmd.StdoutPipe()
+		So(err, ShouldBeNil)
+		So(cmd.Start(), ShouldBeNil)
+
+		line, err := bufio.NewReader(stdout).ReadString('\n')
+		So(err, ShouldBeNil)
+		child, err := strconv.Atoi(strings.TrimSpace(line))
+		So(err, ShouldBeNil)
+
+		mg := NewMemoryGuard(cmd.Process)
+		mg.Interval = 100 * time.Millisecond
+		mg.SetKillGroup()
+
+		Convey("and set a really low threshold, the whole group gets killed", func() {
+			defer mg.Cancel()
+			mg.Limit(1024) // 1KB
+
+			<-mg.KillChan // wait for the kill
+			cmd.Wait()
+
+			So(cmd.ProcessState.Success(), ShouldBeFalse)
+			time.Sleep(100 * time.Millisecond)
+
+			// The child is gone, or a zombie waiting to be reaped
+			stat, err := os.ReadFile("/proc/" + strconv.Itoa(child) + "/stat")
+			if err == nil {
+				So(string(stat), ShouldContainSubstring, ") Z ")
+			}
+		})
+	})
+}
+
+func Test_MemoryGuardPause(t *testing.T) {
+	Convey("When a MemoryGuard is running on us, and is paused", t, func() {
+		us, _ := os.FindProcess(os.Getpid())
+		mg := NewMemoryGuard(us)
+		mg.Interval = 10 * time.Millisecond
+		mg.SetNoKill()
+		mg.Pause()
+		defer mg.Cancel()
+
+		Convey("and set a really low threshold, we won't get killed until it is resumed", func() {
+			mg.Limit(1024) // 1KB
+
+			select {
+			case <-mg.KillChan:
+				So("killed while paused", ShouldBeEmpty)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			mg.Resume()
+			select {
+			case <-mg.KillChan:
+			case <-time.After(time.Second):
+				So("not killed after resume", ShouldBeEmpty)
+			}
+		})
+	})
+}
@@ -11,6 +11,14 @@ const (
 	Exec    = Verb("exec")
 	Send    = Verb("send")
 	Connect = Verb("connect")
+	History = Verb("history")
+	Events  = Verb("events")
+	Pause   = Verb("pause")
+	Resume  = Verb("resume")
+	Signal  = Verb("signal")
+	Reset   = Verb("reset")
+	Health  = Verb("health")
+	Tail    = Verb("tail")
 	NilVerb = Verb("")
 )
 
@@ -35,6 +43,8 @@ type Request struct {
 	Data    string
 	Waiting bool
 	Chan    chan Response
+	// Input is what the client sends after the request, for session Verbs (Connect), or nil.
+	Input io.Reader
 }
 
 // Response is a structure to respond if a Request expects it.
@@ -45,6 +55,8 @@ type Response struct {
 	Data io.ReadCloser
 	// if Error != nil, Data is undefined.
 	Error error
+	// If Stream, Data is read and sent to the client as it comes, until EOF, instead of all at once.
+	Stream bool
 }
 
 // ToVerb returns the Verb of the string, or NilVerb.
@@ -60,11 +72,33 @@ func ToVerb(s string) Verb {
 		return Exec
 	case "connect":
 		return Connect
+	case "history":
+		return History
+	case "events":
+		return Events
+	case "pause":
+		return Pause
+	case "resume":
+		return Resume
+	case "signal":
+		return Signal
+	case "reset":
+		return Reset
+	case "health":
+		return Health
+	case "tail":
+		return Tail
 	default:
 		return NilVerb
 	}
 }
 
+// IsSession returns true if the Verb opens a long-lived session, where the client keeps sending Input
+// after the request, until it detaches.
+func IsSession(v Verb) bool {
+	return v == Connect
+}
+
 // ToNoun returns the Noun of the string, or NilNoun.
 func ToNoun(s string) Noun {
 	switch s {
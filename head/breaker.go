@@ -0,0 +1,88 @@
+package head
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cognusion/go-slippycounter"
+)
+
+// Reset closes a tripped breaker, so the Head restarts immediately
+func (r *Head) Reset() error {
+	r.breakerLock.Lock()
+	defer r.breakerLock.Unlock()
+
+	if r.breaker == nil {
+		return fmt.Errorf("breaker not tripped")
+	}
+	close(r.breaker)
+	r.breaker = nil
+	return nil
+}
+
+// Tripped returns true if the breaker is tripped, and the Head is not restarting
+func (r *Head) Tripped() bool {
+	r.breakerLock.Lock()
+	defer r.breakerLock.Unlock()
+	return r.breaker != nil
+}
+
+// clearBreaker clears the tripped breaker, if Reset hasn't already
+func (r *Head) clearBreaker(reset chan struct{}) {
+	r.breakerLock.Lock()
+	defer r.breakerLock.Unlock()
+	if r.breaker == reset {
+		r.breaker = nil
+	}
+}
+
+// breakerTrips returns true if BreakerRPM is set, and has been exceeded
+func (r *Head) breakerTrips() bool {
+	return r.BreakerRPM > 0 && r.RestartsPerMinute() > r.BreakerRPM
+}
+
+// tripBreaker trips the breaker, and blocks until BreakerCooldown has passed, or Reset is
+// called, or the Head is stopped. It returns false if the Head was stopped.
+func (r *Head) tripBreaker(name string) bool {
+	rpm := r.RestartsPerMinute()
+
+	r.breakerLock.Lock()
+	reset := make(chan struct{})
+	r.breaker = reset
+	r.breakerLock.Unlock()
+
+	var cooldown <-chan time.Time
+	if r.BreakerCooldown > 0 {
+		r.setState(StateBackoff)
+		cooldown = time.After(r.BreakerCooldown)
+	} else {
+		r.setState(StateFailed)
+	}
+
+	err := fmt.Errorf("%d restarts in the last minute exceeds %d", rpm, r.BreakerRPM)
+	r.DebugOut.Printf("%s/%s Breaker tripped: %s", name, r.ID, err)
+	r.emit(Event{Type: EventBreakerTripped, Name: name, ExitCode: -1, Error: err})
+
+	select {
+	case <-r.ctx.Done():
+		// Stopped, so no longer tripped
+		r.clearBreaker(reset)
+		return false
+	case <-reset:
+	case <-cooldown:
+		r.clearBreaker(reset)
+	}
+
+	// Start counting afresh, or it will trip again straight away
+	r.restartsLock.Lock()
+	if r.ctx.Err() == nil {
+		r.restartsMin.Close()
+		r.restartsMin = slippycounter.NewSlippyCounter(1 * time.Minute)
+	}
+	r.restartsLock.Unlock()
+
+	r.setState(StateBackoff)
+	r.DebugOut.Printf("%s/%s Breaker reset", name, r.ID)
+	r.emit(Event{Type: EventBreakerReset, Name: name, ExitCode: -1})
+	return true
+}
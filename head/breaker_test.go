@@ -0,0 +1,76 @@
+package head
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_HeadBreaker(t *testing.T) {
+	errorChan := make(chan error, 100)
+
+	Convey("When a Head's breaker isn't tripped, it can't be reset", t, func() {
+		r := New("false", []string{}, errorChan)
+		defer r.Stop()
+
+		So(r.Tripped(), ShouldBeFalse)
+		So(r.Reset(), ShouldNotBeNil)
+	})
+
+	Convey("When a crash-looping Head has a BreakerRPM and no BreakerCooldown", t, func() {
+		events := make(chan Event, 100)
+		r := New("false", []string{}, errorChan)
+		r.Autorestart(true)
+		r.RestartDelay = 10 * time.Millisecond
+		r.BreakerRPM = 2
+		r.Subscribe(events)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(300 * time.Millisecond)
+
+		Convey("the breaker trips, and the Head has failed", func() {
+			So(r.Tripped(), ShouldBeTrue)
+			So(r.Restarts(), ShouldEqual, 3)
+			So(r.Instance().State, ShouldEqual, StateFailed)
+			So(eventTypes(events), ShouldContain, EventBreakerTripped)
+
+			Convey("and when it is reset, it restarts until it trips again", func() {
+				So(r.Reset(), ShouldBeNil)
+				time.Sleep(300 * time.Millisecond)
+				So(r.Tripped(), ShouldBeTrue)
+				So(r.Restarts(), ShouldEqual, 6)
+				So(eventTypes(events), ShouldContain, EventBreakerReset)
+			})
+
+			Convey("and when it is stopped, it finishes, and is no longer tripped", func() {
+				r.Stop()
+				r.Wait()
+				So(r.Restarts(), ShouldEqual, 3)
+				So(r.Tripped(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("When a crash-looping Head has a BreakerRPM and a BreakerCooldown", t, func() {
+		r := New("false", []string{}, errorChan)
+		r.Autorestart(true)
+		r.RestartDelay = 10 * time.Millisecond
+		r.BreakerRPM = 2
+		r.BreakerCooldown = 300 * time.Millisecond
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(200 * time.Millisecond)
+
+		Convey("the breaker trips, and the Head backs off until it cools down", func() {
+			So(r.Tripped(), ShouldBeTrue)
+			So(r.Instance().State, ShouldEqual, StateBackoff)
+			So(r.Restarts(), ShouldEqual, 3)
+
+			time.Sleep(300 * time.Millisecond)
+			So(r.Restarts(), ShouldBeGreaterThan, 3)
+		})
+	})
+}
@@ -3,29 +3,63 @@ package head
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 // ReadLogger continuously reads from an io.Reader, and blurts to the specified log.Logger
 func ReadLogger(reader io.Reader, writer *log.Logger, errorChan chan<- error) {
+	readLines(reader, errorChan, func(line []byte, partial bool) {
+		if partial {
+			writer.Printf("%s", line)
+		} else if len(line) > 0 {
+			writer.Printf("%s\n", line)
+		}
+	})
+}
+
+// maxLineLength is the most of a line readLines holds on to, before handing it on in pieces
+const maxLineLength = 64 * 1024
+
+// readLines continuously reads from an io.Reader, handing each whole line, without its
+// newline, to onLine. Lines longer than maxLineLength are handed on in pieces, with
+// partial true for all but the last, so output that never sends a newline can't eat
+// all of our memory. The line is only valid until onLine returns.
+func readLines(reader io.Reader, errorChan chan<- error, onLine func(line []byte, partial bool)) {
 
 	// Reader, instead of Scanner, so we can handle lines > 64k :(
 	in := bufio.NewReader(reader)
 
+	var partial []byte // a line longer than the buffer, or what's left of one handed on in pieces
 	for {
 		line, prefix, err := in.ReadLine()
-		if len(line) > 0 {
-			if prefix {
-				writer.Printf("%s", line)
-			} else {
-				writer.Printf("%s\n", line)
-			}
+		switch {
+		case prefix && len(partial) > 0 && len(partial)+len(line) > maxLineLength:
+			// Hand on what we have, but hold this back, so the last piece is never empty
+			onLine(partial, true)
+			partial = append(partial[:0], line...)
+		case prefix:
+			partial = append(partial, line...)
+		case len(partial) > 0:
+			onLine(append(partial, line...), false)
+			partial = partial[:0]
+		case len(line) > 0 || err == nil:
+			onLine(line, false)
 		}
 
 		if err != nil {
-			if err != io.EOF {
+			// The pipe is closed out from under us if anything the process left behind
+			// holds it open after it exits, so ErrClosed is just another way of saying EOF.
+			if err != io.EOF && !errors.Is(err, os.ErrClosed) {
 				// We want to send the error,
 				// but errorChan could be closed
 				select {
@@ -38,6 +72,27 @@ func ReadLogger(reader io.Reader, writer *log.Logger, errorChan chan<- error) {
 	}
 }
 
+// ParseSignal takes a signal name ("TERM", "SIGTERM", "term") or number ("15"),
+// and returns the corresponding syscall.Signal, or an error if it is not known.
+func ParseSignal(s string) (syscall.Signal, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || unix.SignalName(syscall.Signal(n)) == "" {
+			return 0, fmt.Errorf("unknown signal number '%d'", n)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	name := strings.ToUpper(s)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal '%s'", s)
+}
+
 // Sbuffer is a goro-safe bytes.Buffer
 type Sbuffer struct {
 	buffer bytes.Buffer
@@ -6,6 +6,8 @@ import (
 
 	"bytes"
 	"log"
+	"strings"
+	"syscall"
 	"testing"
 )
 
@@ -35,3 +37,69 @@ func Test_BufioHuge(t *testing.T) {
 		So(e, ShouldBeNil)
 	})
 }
+
+func Test_ParseSignal(t *testing.T) {
+	Convey("When signals are parsed", t, func() {
+		Convey("names, prefixed names, lowercase names, and numbers are all understood", func() {
+			for _, s := range []string{"TERM", "SIGTERM", "term", "15", " 15 "} {
+				sig, err := ParseSignal(s)
+				So(err, ShouldBeNil)
+				So(sig, ShouldEqual, syscall.SIGTERM)
+			}
+
+			sig, err := ParseSignal("HUP")
+			So(err, ShouldBeNil)
+			So(sig, ShouldEqual, syscall.SIGHUP)
+		})
+
+		Convey("nonsense is an error", func() {
+			for _, s := range []string{"", "BOB", "SIGBOB", "0", "-1", "1000"} {
+				_, err := ParseSignal(s)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func Test_ReadLoggerLines(t *testing.T) {
+	Convey("When readLines reads, it hands on whole lines, however long", t, func() {
+		long := bytes.Repeat([]byte("x"), 10000)
+		in := bytes.NewBufferString("one\n\n")
+		in.Write(long)
+		in.WriteString("\ntwo")
+
+		var lines []string
+		readLines(in, make(chan error, 1), func(line []byte, partial bool) {
+			So(partial, ShouldBeFalse)
+			lines = append(lines, string(line))
+		})
+
+		So(lines, ShouldResemble, []string{"one", "", string(long), "two"})
+	})
+}
+
+func Test_ReadLinesEndless(t *testing.T) {
+	Convey("When readLines reads a line longer than maxLineLength, it hands it on in pieces, all but the last partial", t, func() {
+		long := bytes.Repeat([]byte("x"), 3*maxLineLength)
+		in := bytes.NewBuffer(long)
+		in.WriteString("\ntwo\n")
+
+		var (
+			lines    []string
+			partials []bool
+		)
+		readLines(in, make(chan error, 1), func(line []byte, partial bool) {
+			So(len(line), ShouldBeBetweenOrEqual, 1, maxLineLength+4096)
+			lines = append(lines, string(line))
+			partials = append(partials, partial)
+		})
+
+		last := len(lines) - 1
+		So(strings.Join(lines[:last], ""), ShouldEqual, string(long))
+		So(lines[last], ShouldEqual, "two")
+		So(partials[last-1], ShouldBeFalse)
+		for _, p := range partials[:last-1] {
+			So(p, ShouldBeTrue)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package head
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// EventType is the kind of lifecycle Event a Head emits
+type EventType string
+
+// EventTypes
+const (
+	// EventStarting is emitted before a process is started
+	EventStarting = EventType("starting")
+	// EventStarted is emitted once a process has started, and has a PID
+	EventStarted = EventType("started")
+	// EventStartFailed is emitted if a process could not be started
+	EventStartFailed = EventType("startfailed")
+	// EventReady is emitted once a process passes its ReadyProbe or matches its ReadyPattern
+	EventReady = EventType("ready")
+	// EventExited is emitted when a process exits on its own
+	EventExited = EventType("exited")
+	// EventTimedOut is emitted when a process is stopped for exceeding the Timeout
+	EventTimedOut = EventType("timedout")
+	// EventLivenessFailed is emitted when a process is killed for failing its LiveProbe
+	EventLivenessFailed = EventType("livenessfailed")
+	// EventMemoryExceeded is emitted when a process is killed for exceeding MaxPSS
+	EventMemoryExceeded = EventType("memoryexceeded")
+	// EventStopped is emitted when a process is stopped via Stop()
+	EventStopped = EventType("stopped")
+	// EventPaused is emitted when a process is paused via Pause()
+	EventPaused = EventType("paused")
+	// EventResumed is emitted when a process is resumed via Resume()
+	EventResumed = EventType("resumed")
+	// EventBreakerTripped is emitted when restarts are held for exceeding BreakerRPM
+	EventBreakerTripped = EventType("breakertripped")
+	// EventBreakerReset is emitted when a tripped breaker cools down, or is Reset()
+	EventBreakerReset = EventType("breakerreset")
+	// EventTriggered is emitted when a Trigger fires, before its Action
+	EventTriggered = EventType("triggered")
+	// EventTriggerKilled is emitted when a process is killed by a Trigger with TriggerRestart
+	EventTriggerKilled = EventType("triggerkilled")
+	// EventRestarting is emitted before waiting to restart a process
+	EventRestarting = EventType("restarting")
+)
+
+// exitEvents maps how a run ended to the Event emitted for it
+var exitEvents = map[ExitReason]EventType{
+	ExitNormal:      EventExited,
+	ExitStopped:     EventStopped,
+	ExitTimeout:     EventTimedOut,
+	ExitMemory:      EventMemoryExceeded,
+	ExitLiveness:    EventLivenessFailed,
+	ExitTriggered:   EventTriggerKilled,
+	ExitStartFailed: EventStartFailed,
+}
+
+// Event is a lifecycle event of a Head's process
+type Event struct {
+	// Type is the kind of Event
+	Type EventType
+	// Time is when the Event happened
+	Time time.Time
+	// HeadID is the ID of the Head
+	HeadID string
+	// Name is the instance name of the Head's run
+	Name string
+	// PID is the process ID, or 0 if there isn't one (yet)
+	PID int
+	// ExitCode is the exit code of the process, or -1 if it hasn't exited, was signalled, or never started
+	ExitCode int
+	// Signal is the signal that terminated the process, or nil
+	Signal os.Signal
+	// Error is the error associated with the Event, if any
+	Error error
+}
+
+// String returns the Event in a line
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s/%s %s pid=%d", e.Time.Format(time.RFC3339), e.Name, e.HeadID, e.Type, e.PID)
+	switch {
+	case e.Signal != nil:
+		s += fmt.Sprintf(" signal=%s", e.Signal)
+	case e.ExitCode >= 0:
+		s += fmt.Sprintf(" exit=%d", e.ExitCode)
+	}
+	if e.Error != nil {
+		s += fmt.Sprintf(" error=%q", e.Error)
+	}
+	return s
+}
+
+// Ended returns true if the Event is for the end of a run of the process, however it ended
+func (e Event) Ended() bool {
+	for _, t := range exitEvents {
+		if e.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe registers a channel to receive this Head's Events. Events are dropped rather
+// than block the Head, so the channel should be buffered, and read from promptly.
+func (r *Head) Subscribe(ch chan<- Event) {
+	r.subLock.Lock()
+	defer r.subLock.Unlock()
+	r.subscribers = append(r.subscribers, ch)
+}
+
+// Unsubscribe stops sending Events to a channel previously passed to Subscribe.
+func (r *Head) Unsubscribe(ch chan<- Event) {
+	r.subLock.Lock()
+	defer r.subLock.Unlock()
+	for i, s := range r.subscribers {
+		if s == ch {
+			r.subscribers = append(r.subscribers[:i:i], r.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// emit sends an Event to every subscriber that has room for it
+func (r *Head) emit(e Event) {
+	e.HeadID = r.ID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	r.subLock.Lock()
+	defer r.subLock.Unlock()
+	for _, ch := range r.subscribers {
+		select {
+		case ch <- e:
+		default:
+			r.DebugOut.Printf("Event channel full, dropping event: %s\n", e)
+		}
+	}
+}
+
+// emitRecord emits the Event for how the run in the RunRecord ended
+func (r *Head) emitRecord(rr RunRecord, err error) {
+	r.emit(Event{
+		Type:     exitEvents[rr.Reason],
+		Time:     rr.End,
+		Name:     rr.Name,
+		PID:      rr.PID,
+		ExitCode: rr.ExitCode,
+		Signal:   rr.Signal,
+		Error:    err,
+	})
+}
@@ -0,0 +1,138 @@
+package head
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// eventTypes drains the Events currently in the channel, and returns their types
+func eventTypes(events chan Event) []EventType {
+	var types []EventType
+	for {
+		select {
+		case e := <-events:
+			types = append(types, e.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func Test_HeadEvents(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head with a subscriber runs a command that fails", t, func() {
+		events := make(chan Event, 10)
+		r := New("false", nil, errorChan)
+		defer r.Stop()
+		r.ID = "bob"
+		r.Subscribe(events)
+
+		Convey("the subscriber is told it is starting, started, and exited, with the details", func() {
+			r.Run()
+			r.Wait()
+
+			e := <-events
+			So(e.Type, ShouldEqual, EventStarting)
+			So(e.HeadID, ShouldEqual, "bob")
+			So(e.Name, ShouldNotBeZeroValue)
+
+			e = <-events
+			So(e.Type, ShouldEqual, EventStarted)
+			So(e.PID, ShouldBeGreaterThan, 0)
+			So(e.Ended(), ShouldBeFalse)
+
+			e = <-events
+			So(e.Type, ShouldEqual, EventExited)
+			So(e.ExitCode, ShouldEqual, 1)
+			So(e.Signal, ShouldBeNil)
+			So(e.Error, ShouldNotBeNil)
+			So(e.String(), ShouldContainSubstring, "/bob exited pid=")
+			So(e.Ended(), ShouldBeTrue)
+		})
+	})
+
+	Convey("When a Head with a subscriber is stopped", t, func() {
+		events := make(chan Event, 10)
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+		r.Subscribe(events)
+
+		Convey("the subscriber is told it stopped, and by what", func() {
+			r.Run()
+			time.Sleep(100 * time.Millisecond)
+			r.Stop()
+			r.Wait()
+
+			So(eventTypes(events), ShouldResemble, []EventType{EventStarting, EventStarted, EventStopped})
+		})
+	})
+
+	Convey("When a Head with a subscriber times out", t, func() {
+		events := make(chan Event, 10)
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+		r.Timeout = 100 * time.Millisecond
+		r.Subscribe(events)
+
+		Convey("the subscriber is told it timed out", func() {
+			r.Run()
+			r.Wait()
+
+			So(eventTypes(events), ShouldResemble, []EventType{EventStarting, EventStarted, EventTimedOut})
+		})
+	})
+
+	Convey("When a Head with a subscriber cannot start", t, func() {
+		events := make(chan Event, 10)
+		r := New("/this/does/not/exist", nil, errorChan)
+		defer r.Stop()
+		r.Subscribe(events)
+
+		Convey("the subscriber is told it failed, and why", func() {
+			r.Run()
+			r.Wait()
+
+			<-events // starting
+			e := <-events
+			So(e.Type, ShouldEqual, EventStartFailed)
+			So(e.Error, ShouldWrap, syscall.ENOENT)
+		})
+	})
+
+	Convey("When a Head with a subscriber autorestarts", t, func() {
+		events := make(chan Event, 10)
+		r := New("true", nil, errorChan)
+		defer r.Stop()
+		r.Autorestart(true)
+		r.RestartDelay = time.Second
+		r.Subscribe(events)
+
+		Convey("the subscriber is told it is restarting", func() {
+			r.Run()
+			time.Sleep(500 * time.Millisecond)
+			r.Stop()
+			r.Wait()
+
+			So(eventTypes(events), ShouldResemble, []EventType{EventStarting, EventStarted, EventExited, EventRestarting})
+		})
+	})
+
+	Convey("When a Head's subscriber Unsubscribes", t, func() {
+		events := make(chan Event, 10)
+		r := New("true", nil, errorChan)
+		defer r.Stop()
+		r.Subscribe(events)
+		r.Unsubscribe(events)
+
+		Convey("it is told nothing", func() {
+			r.Run()
+			r.Wait()
+
+			So(eventTypes(events), ShouldBeEmpty)
+		})
+	})
+}
@@ -10,11 +10,14 @@ import (
 	sq "github.com/Hellseher/go-shellquote"
 
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -27,8 +30,48 @@ import (
 type Head struct {
 	// ID is a generated ID based on the sequence
 	ID string
-	// RestartDelay specified the duration to wait between restarts
+	// RestartDelay specified the duration to wait between restarts, if RestartPolicy is nil
 	RestartDelay time.Duration
+	// RestartPolicy decides the duration to wait between restarts. If nil, RestartDelay is used
+	RestartPolicy RestartPolicy
+	// RestartOn is the condition under which a completed process is restarted, if Autorestart
+	// is enabled. Default RestartAlways
+	RestartOn RestartCondition
+	// SuccessExitCodes are exit codes, in addition to 0, that are considered a clean exit
+	SuccessExitCodes []int
+	// RestartPreventExitCodes are exit codes that will never be restarted, regardless of RestartOn
+	RestartPreventExitCodes []int
+	// RestartPreventSignals are terminating signals that will never be restarted, regardless of RestartOn
+	RestartPreventSignals []os.Signal
+	// HistorySize is the number of RunRecords kept for History(). Negative keeps all of them,
+	// and 0 none. Default 10
+	HistorySize int
+	// TransitionLogSize is the number of Transitions kept for Transitions(). Negative keeps all
+	// of them, and 0 none. Default 50
+	TransitionLogSize int
+	// TailSize is the number of Lines of each of stdout and stderr kept for Tail(). Default 100
+	TailSize int
+	// OutputSinks receive each Line the processes output, in addition to StdOut or StdErr
+	OutputSinks []OutputSink
+	// PTY runs the processes on a pseudo-terminal, instead of pipes. Their stdout and stderr
+	// are both read as StreamStdout, and the terminal doesn't echo what is written to stdin
+	PTY bool
+	// PTYRows and PTYCols are the window size of the PTY. Default 24x80
+	PTYRows uint16
+	PTYCols uint16
+	// Triggers are rules on the output of the processes, that fire actions
+	Triggers []Trigger
+	// OutputPrefix, if set, is printed before each line printed to StdOut or StdErr, after
+	// its macros are expanded by ExpandPrefix, e.g. "[{instance}] "
+	OutputPrefix string
+	// StableUptime is how long a process must run before the RestartPolicy starts over from
+	// the first attempt. If 0, it never does
+	StableUptime time.Duration
+	// BreakerRPM, if set, trips the breaker when a process needs restarting after more than
+	// BreakerRPM restarts in the last minute. A tripped breaker holds restarts for BreakerCooldown
+	BreakerRPM uint64
+	// BreakerCooldown is the duration a tripped breaker holds restarts. If 0, until Reset()
+	BreakerCooldown time.Duration
 	// MaxPSS specifies the maximum PSS size a process may have before being killed
 	MaxPSS int64
 	// DebugOut is a logger for debug information
@@ -49,6 +92,34 @@ type Head struct {
 	Values sync.Map
 	// Timeout is a duration after which the process running is stopped, subject to  Autorestart
 	Timeout time.Duration
+	// StopSignal is the signal sent to a process when it is stopped or times out. Default SIGTERM
+	StopSignal os.Signal
+	// StopTimeout is the duration to wait after StopSignal before the process is killed.
+	// If 0, the process is killed immediately. Default 10s
+	StopTimeout time.Duration
+	// ProcessGroup starts each process in its own process group, so that stops, timeouts and
+	// memory guard kills reach the whole process tree instead of only the direct child
+	ProcessGroup bool
+	// ReadyProbe, if set, is run every ReadyInterval once a process has started, and moves
+	// the Head from StateStarting to StateReady when it passes
+	ReadyProbe Probe
+	// ReadyPattern, if set, moves the Head from StateStarting to StateReady when a line of
+	// stdout or stderr matches it
+	ReadyPattern *regexp.Regexp
+	// ReadyInterval is the duration to wait between ReadyProbe attempts, and the timeout
+	// of each. Default 1s
+	ReadyInterval time.Duration
+	// LiveProbe, if set, is run every LiveInterval once a process has started, after LiveDelay.
+	// After LiveFailures consecutive failures the process is killed, subject to Autorestart
+	LiveProbe Probe
+	// LiveInterval is the duration to wait between LiveProbe attempts. Default 10s
+	LiveInterval time.Duration
+	// LiveTimeout is the duration a LiveProbe attempt may take before it fails. Default 1s
+	LiveTimeout time.Duration
+	// LiveFailures is the number of consecutive LiveProbe failures that kill the process. Default 3
+	LiveFailures int
+	// LiveDelay is the duration to wait after a process starts before the first LiveProbe
+	LiveDelay time.Duration
 	// StdInNoNL is a boolean to describe if a NewLine should *not* be appended to lines written to StdIn.
 	// This is advisory-only, and respected by hydra but not necessarily others.
 	StdInNoNL bool
@@ -58,6 +129,7 @@ type Head struct {
 
 	wg           sync.WaitGroup
 	restarts     uint64
+	runs         uint64
 	errors       uint64
 	errorChan    chan<- error
 	rawErrorChan chan error
@@ -66,12 +138,34 @@ type Head struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	restartsMin  *slippycounter.SlippyCounter
+	restartsLock sync.Mutex
 	mgInterval   time.Duration
-	status       atomic.Value
 	autoRestart  atomic.Value
 	stdIn        io.WriteCloser
 	stdInLock    sync.Mutex
 	childEnv     []string
+	history      []RunRecord
+	subscribers  []chan<- Event
+	subLock      sync.Mutex
+	instance     Instance
+	state        State
+	resumeState  State
+	transitions  []Transition
+	active       bool
+	stopAfter    func() bool
+	stateLock    sync.Mutex
+	breaker      chan struct{}
+	breakerLock  sync.Mutex
+	paused       chan struct{}
+	runTimer     *pauseTimer
+	runGuard     *athena.MemoryGuard
+	pauseLock    sync.Mutex
+	historyLock  sync.Mutex
+	tails        map[Stream]*lineRing
+	tailLock     sync.Mutex
+	attached     map[uint64]OutputSink
+	attachSeq    uint64
+	attachLock   sync.Mutex
 }
 
 // BashDashC creates a head that handles the command in its entirety running as a "bash -c command"
@@ -86,21 +180,28 @@ func New(command string, args []string, errorChan chan error) *Head {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	r := Head{
-		command:      command,
-		args:         args,
-		errorChan:    errorChan,
-		rawErrorChan: errorChan,
-		restarts:     0,
-		ctx:          ctx,
-		cancel:       cancel,
-		DebugOut:     log.New(io.Discard, "", 0),
-		ErrOut:       log.New(io.Discard, "", 0),
-		StdOut:       log.New(io.Discard, "", 0),
-		StdErr:       log.New(io.Discard, "", 0),
-		restartsMin:  slippycounter.NewSlippyCounter(1 * time.Minute),
-		mgInterval:   30 * time.Second,
+		command:           command,
+		args:              args,
+		errorChan:         errorChan,
+		rawErrorChan:      errorChan,
+		restarts:          0,
+		ctx:               ctx,
+		cancel:            cancel,
+		DebugOut:          log.New(io.Discard, "", 0),
+		ErrOut:            log.New(io.Discard, "", 0),
+		StdOut:            log.New(io.Discard, "", 0),
+		StdErr:            log.New(io.Discard, "", 0),
+		restartsMin:       slippycounter.NewSlippyCounter(1 * time.Minute),
+		mgInterval:        30 * time.Second,
+		StopSignal:        syscall.SIGTERM,
+		StopTimeout:       10 * time.Second,
+		HistorySize:       10,
+		TransitionLogSize: 50,
+		TailSize:          100,
+		PTYRows:           24,
+		PTYCols:           80,
+		state:             StateInit,
 	}
-	r.status.Store("init")
 	r.autoRestart.Store(false)
 	return &r
 }
@@ -110,6 +211,23 @@ func (r *Head) Clone() *Head {
 	c := New(r.command, r.args, r.rawErrorChan)
 
 	c.RestartDelay = r.RestartDelay
+	c.RestartPolicy = r.RestartPolicy
+	c.StableUptime = r.StableUptime
+	c.BreakerRPM = r.BreakerRPM
+	c.BreakerCooldown = r.BreakerCooldown
+	c.HistorySize = r.HistorySize
+	c.TransitionLogSize = r.TransitionLogSize
+	c.TailSize = r.TailSize
+	c.OutputSinks = slices.Clone(r.OutputSinks)
+	c.OutputPrefix = r.OutputPrefix
+	c.Triggers = r.Triggers
+	c.PTY = r.PTY
+	c.PTYRows = r.PTYRows
+	c.PTYCols = r.PTYCols
+	c.RestartOn = r.RestartOn
+	c.SuccessExitCodes = r.SuccessExitCodes
+	c.RestartPreventExitCodes = r.RestartPreventExitCodes
+	c.RestartPreventSignals = r.RestartPreventSignals
 	c.MaxPSS = r.MaxPSS
 	c.DebugOut = r.DebugOut
 	c.ErrOut = r.ErrOut
@@ -120,8 +238,26 @@ func (r *Head) Clone() *Head {
 	c.Seq = r.Seq
 	c.Values = *copyValues(&r.Values)
 	c.Timeout = r.Timeout
+	c.StopSignal = r.StopSignal
+	c.StopTimeout = r.StopTimeout
+	c.ProcessGroup = r.ProcessGroup
+	c.ReadyProbe = r.ReadyProbe
+	c.ReadyPattern = r.ReadyPattern
+	c.ReadyInterval = r.ReadyInterval
+	c.LiveProbe = r.LiveProbe
+	c.LiveInterval = r.LiveInterval
+	c.LiveTimeout = r.LiveTimeout
+	c.LiveFailures = r.LiveFailures
+	c.LiveDelay = r.LiveDelay
 	c.StdInNoNL = r.StdInNoNL
 	c.StdInShellEscapeInput = r.StdInShellEscapeInput
+	c.childEnv = r.childEnv
+	c.mgInterval = r.mgInterval
+	c.autoRestart.Store(r.autoRestart.Load())
+
+	r.subLock.Lock()
+	c.subscribers = slices.Clone(r.subscribers)
+	r.subLock.Unlock()
 
 	return c
 }
@@ -130,6 +266,9 @@ func (r *Head) Clone() *Head {
 func (r *Head) Write(p []byte) (n int, err error) {
 	r.stdInLock.Lock()
 	defer r.stdInLock.Unlock()
+	if r.stdIn == nil {
+		return 0, ErrNoStdin
+	}
 	return r.stdIn.Write(p)
 }
 
@@ -138,7 +277,8 @@ func (r *Head) SetChildEnv(env []string) {
 	r.childEnv = env
 }
 
-// Autorestart sets whether or not we will automatically restart Heads that "complete"
+// Autorestart sets whether or not we will automatically restart Heads that "complete",
+// subject to RestartOn
 func (r *Head) Autorestart(doit bool) {
 	r.autoRestart.Store(doit)
 }
@@ -155,23 +295,31 @@ func (r *Head) String() string {
 }
 
 // Run executes a subprocess of the command and arguments specified, restarting
-// it if applicable. The returned channel returns the name of the once it is
-// running, or closes it without a value if it will not run.
+// it if applicable, and returns the macro-expanded command line. If the Head is
+// already running, nothing is done and "" is returned; State() tells which.
 func (r *Head) Run() string {
+	return r.RunContext(context.Background())
+}
 
-	if r.status.Load() == "running" {
+// RunContext is Run, but the Head is stopped when ctx is done.
+func (r *Head) RunContext(ctx context.Context) string {
+
+	r.stateLock.Lock()
+	if r.active || !r.transition(StateStarting) {
+		r.stateLock.Unlock()
 		return ""
 	}
-
+	r.active = true
+	r.stopAfter = context.AfterFunc(ctx, r.Stop)
 	r.wg.Add(1)
-	r.status.Store("running")
+	r.stateLock.Unlock()
 
 	stringChan := make(chan string, 1)
 	procname := randomnames.SafeRandomAdjectiveAnimal()
 
 	go func(name string, s chan<- string) {
 		defer r.wg.Done()
-		defer r.status.Store("done")
+		defer r.finish()
 
 		// Make the [short]name macro
 		re := regexp.MustCompile(`\W`)
@@ -205,25 +353,51 @@ func (r *Head) Run() string {
 		r.DebugOut.Printf("%s/%s Starting (%s)...", name, r.ID, shortname)
 		defer r.DebugOut.Printf("%s/%s Exiting (%s)...", name, r.ID, shortname)
 
+		var attempt int // consecutive restarts, for the RestartPolicy
 		for {
 			var (
 				mg      *athena.MemoryGuard
+				timer   *pauseTimer
 				cmd     *exec.Cmd
-				lcancel = func() {}
-				lctx    = r.ctx
+				started = time.Now()
+				killed  bool // by the Head, for a Timeout, failed LiveProbe or Trigger
 			)
 
+			// A local context, so a Timeout, failed LiveProbe or Trigger can kill this process, with cause
+			lctx, kill := context.WithCancelCause(r.ctx)
+			lcancel := func() {
+				if timer != nil {
+					timer.Stop()
+				}
+				kill(context.Canceled)
+			}
 			if r.Timeout > 0 {
-				// Timeout, remap the context
-				lctx, lcancel = context.WithTimeout(r.ctx, r.Timeout)
+				// The timer is pausable, so isn't context.WithTimeout, but the cause is the same.
+				timer = newPauseTimer(r.Timeout, func() { kill(context.DeadlineExceeded) })
 			}
 
 			//#nosec G204 -- Yes. We have to trust the configs.
 			cmd = exec.CommandContext(lctx, lcommand, largs...)
 
+			var sweep *groupSweep
+			if r.ProcessGroup {
+				// The group is signalled, and swept after StopTimeout.
+				cmd.Cancel = func() (err error) {
+					sweep, err = r.stopGroup(name, cmd.Process.Pid)
+					return err
+				}
+			} else if r.StopTimeout > 0 && r.StopSignal != nil {
+				// When either context is done, ask nicely first, and let
+				// exec escalate to a kill after StopTimeout.
+				cmd.Cancel = func() error {
+					return cmd.Process.Signal(r.StopSignal)
+				}
+				cmd.WaitDelay = r.StopTimeout
+			}
+
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: r.ProcessGroup}
 			if r.UID > 0 {
 				// Run as
-				cmd.SysProcAttr = &syscall.SysProcAttr{}
 				cmd.SysProcAttr.Credential = &syscall.Credential{Uid: r.UID, Gid: r.GID}
 				r.DebugOut.Printf("\t%+v\n", cmd.SysProcAttr.Credential)
 			}
@@ -233,35 +407,74 @@ func (r *Head) Run() string {
 				cmd.Env = r.childEnv
 			}
 
-			// grab stderr and stdout and stdin
-			stdout, err := cmd.StdoutPipe()
-			if err != nil {
-				r.errorHandler(fmt.Errorf("%s/%s: 'stdoutpipe' %w", name, r.ID, err))
-			}
-			stderr, err := cmd.StderrPipe()
-			if err != nil {
-				r.errorHandler(fmt.Errorf("%s/%s: 'stderrpipe' %w", name, r.ID, err))
-
+			// grab stderr and stdout and stdin, or a PTY, or abort this run
+			var (
+				readers sync.WaitGroup
+				ready   = newReadiness()
+				output  = r.newRunOutput(name, atomic.AddUint64(&r.runs, 1), r.readyHook(ready), r.newTriggerRun(name, kill))
+				stdout  io.ReadCloser
+				stderr  io.ReadCloser // nil with a PTY
+				stdIn   io.WriteCloser
+				theirs  io.Closer // the pipes' write ends, or the PTY slave, closed once the process has them
+				err     error
+			)
+			if r.PTY {
+				stdout, stdIn, theirs, err = ptyPipes(cmd, r.PTYRows, r.PTYCols)
+			} else {
+				stdout, stderr, stdIn, theirs, err = pipes(cmd)
 			}
-			stdIn, err := cmd.StdinPipe()
 			if err != nil {
-				r.errorHandler(fmt.Errorf("%s/%s: 'stdinpipe' %w", name, r.ID, err))
+				r.errorHandler(fmt.Errorf("%s/%s: %w", name, r.ID, err))
+			} else {
+				// Because races are real:
+				r.stdInLock.Lock()
+				r.stdIn = stdIn
+				r.stdInLock.Unlock()
+
+				// Copy the output to the logs and sinks, watching for readiness.
+				readers.Add(1)
+				go func() {
+					defer readers.Done()
+					readLines(stdout, r.errorChan, output.hook(StreamStdout))
+				}()
+				if stderr != nil {
+					readers.Add(1)
+					go func() {
+						defer readers.Done()
+						readLines(stderr, r.errorChan, output.hook(StreamStderr))
+					}()
+				}
 			}
-			// Because races are real:
-			r.stdInLock.Lock()
-			r.stdIn = stdIn
-			r.stdInLock.Unlock()
-
-			// Copy the output to the logs.
-			go ReadLogger(stdout, r.StdOut, r.errorChan)
-			go ReadLogger(stderr, r.StdErr, r.errorChan)
 
 			// Go go gadget command!
-			if err := cmd.Start(); err != nil {
-				r.errorHandler(fmt.Errorf("%s/%s: 'starting' %w", name, r.ID, err))
+			r.emit(Event{Type: EventStarting, Name: name, ExitCode: -1})
+			r.setInstance(name, 0, StateStarting)
+			if err == nil {
+				if err = cmd.Start(); err != nil {
+					r.errorHandler(fmt.Errorf("%s/%s: 'starting' %w", name, r.ID, err))
+				}
+
+				// The process has its own copies of the write ends, or the PTY
+				theirs.Close()
+			}
+			if err != nil {
 				lcancel()
+				output.exited()
+				if theirs != nil {
+					// Nothing else has the pipes, so the readers are done
+					readers.Wait()
+					closeOutput(stdout, stderr)
+				}
+				r.emitRecord(r.record(name, cmd, started, ExitStartFailed), err)
+
+				if IsPermanentStartError(err) {
+					// Retrying won't help
+					r.DebugOut.Printf("%s/%s Not restarting (permanent): %s", name, r.ID, err)
+					r.setState(StateFailed)
+					return
+				}
 			} else {
-				// We're running!
+				output.started(cmd.Process.Pid)
 
 				// Set up memory guard
 				if r.MaxPSS > 0 {
@@ -270,38 +483,93 @@ func (r *Head) Run() string {
 					mg.DebugOut = r.DebugOut
 					mg.ErrOut = r.ErrOut
 					mg.Interval = r.mgInterval
+					if r.ProcessGroup {
+						mg.SetKillGroup()
+					}
 					mg.Limit(r.MaxPSS * 1024 * 1024)
 				}
+				r.setRun(timer, mg)
+
+				// We're running! But if anyone cares whether it's ready, it's starting until it is
+				state := StateRunning
+				if r.ReadyProbe != nil || r.ReadyPattern != nil {
+					state = StateStarting
+				}
+				r.setInstance(name, cmd.Process.Pid, state)
+				r.emit(Event{Type: EventStarted, Name: name, PID: cmd.Process.Pid, ExitCode: -1})
+
+				// Wait for it to be ready, and watch that it stays alive, if anyone cares
+				var (
+					probes      sync.WaitGroup
+					pctx, pstop = context.WithCancel(lctx)
+				)
+				if r.ReadyProbe != nil || r.ReadyPattern != nil {
+					probes.Add(1)
+					go func(pid int) {
+						defer probes.Done()
+						r.waitReady(pctx, name, pid, ready)
+					}(cmd.Process.Pid)
+				}
+				if r.LiveProbe != nil {
+					probes.Add(1)
+					go func() {
+						defer probes.Done()
+						r.watchLive(pctx, name, kill)
+					}()
+				}
 
 				// Wait until the cmd is done
-				if err := cmd.Wait(); err != nil {
-					select {
-					case <-r.ctx.Done():
-					// Context has been cancelled, don't send errors
+				err = cmd.Wait()
+				output.exited()
+				if sweep != nil {
+					// Wait is done with Cancel, and the group may outlive its leader
+					sweep.finish()
+				}
 
-					case <-lctx.Done():
+				// Drain the output, but anything the process left behind may hold it open
+				r.drainOutput(name, &readers, stdout, stderr)
+				cause := context.Cause(lctx)
+				killed = errors.Is(cause, context.DeadlineExceeded) || errors.Is(cause, ErrLivenessFailed) || errors.Is(cause, ErrTriggered)
+				r.setRun(nil, nil)
+				pstop()
+				probes.Wait()
+				if err != nil {
+					if r.ctx.Err() != nil {
+						// Context has been cancelled, don't send errors
+					} else if lctx.Err() != nil {
 						// Local context cancelled, might matter
-						if lctx.Err() != nil {
-							r.errorHandler(fmt.Errorf("%s/%s: 'local' %w", name, r.ID, lctx.Err()))
-						}
-
-					default:
+						r.errorHandler(fmt.Errorf("%s/%s: 'local' %w", name, r.ID, cause))
+					} else {
 						// Global context is clear
 						r.errorHandler(fmt.Errorf("%s/%s: 'waiting' %w", name, r.ID, err))
 					}
 				}
 
-				// If there's a local context, cancel it
+				// Cancel the local context
 				lcancel()
 
 				if r.MaxPSS > 0 {
 					mg.Cancel()
+
+					select {
+					case <-mg.KillChan:
+						if r.ProcessGroup {
+							// Anything left has escaped the kill
+							r.killGroup(name, cmd.Process.Pid)
+						}
+					default:
+					}
 				}
+
+				r.emitRecord(r.record(name, cmd, started, r.exitReason(cause, mg)), err)
 			}
 
 			if r.autoRestart.Load() == false {
 				// We done.
 				return
+			} else if !r.shouldRestart(cmd.ProcessState, killed) {
+				r.DebugOut.Printf("%s/%s Not restarting (%s): %s", name, r.ID, r.restartOn(), cmd.ProcessState)
+				return
 			}
 
 			select {
@@ -312,10 +580,46 @@ func (r *Head) Run() string {
 			default:
 			}
 
+			if r.StableUptime > 0 && time.Since(started) >= r.StableUptime {
+				// It was up long enough to be considered healthy, so start over
+				attempt = 0
+			}
+
+			if r.breakerTrips() {
+				// Crash-looping, so hold off
+				if !r.tripBreaker(name) {
+					r.DebugOut.Printf("%s/%s Cancelling...", name, r.ID)
+					return
+				}
+				attempt = 0
+			}
+
 			// else do it again.. after a nap, maybe
-			time.Sleep(r.RestartDelay)
-			atomic.AddUint64(&r.restarts, 1)
-			r.restartsMin.Add(1)
+			r.setState(StateBackoff)
+			delay := r.restartDelay(attempt)
+			attempt++
+			r.emit(Event{Type: EventRestarting, Name: name, ExitCode: -1})
+			if delay > 0 {
+				r.DebugOut.Printf("%s/%s Restarting in %s...", name, r.ID, delay)
+				select {
+				case <-r.ctx.Done():
+					// Stop signalled while napping
+					r.DebugOut.Printf("%s/%s Cancelling...", name, r.ID)
+					return
+				case <-time.After(delay):
+				}
+			}
+
+			// Hold restarts while paused
+			r.waitResumed()
+			select {
+			case <-r.ctx.Done():
+				r.DebugOut.Printf("%s/%s Cancelling...", name, r.ID)
+				return
+			default:
+			}
+
+			r.countRestart()
 			r.DebugOut.Printf("%s/%s Restarting...", name, r.ID)
 		}
 	}(procname, stringChan)
@@ -324,13 +628,29 @@ func (r *Head) Run() string {
 	return s
 }
 
-// Stop signals all of the running processes to die. May generate error
-// output thereafter.
+// Stop signals all of the running processes to die, sending StopSignal and
+// killing them if they are still running after StopTimeout. Stop does not block,
+// use Wait() for that. May generate error output thereafter.
 func (r *Head) Stop() {
 	r.DebugOut.Println("Stop signalled")
 	r.autoRestart.Store(false) // prevent more restarts
 	r.cancel()                 // Cancel the global context
-	r.restartsMin.Close()      // Close the slippy counter
+	if r.Paused() {
+		r.Resume() // A frozen process can't act on StopSignal
+	}
+
+	r.stateLock.Lock()
+	if r.active {
+		r.transition(StateStopping)
+	} else {
+		r.transition(StateStopped)
+	}
+	r.stateLock.Unlock()
+
+	// Close the slippy counter
+	r.restartsLock.Lock()
+	r.restartsMin.Close()
+	r.restartsLock.Unlock()
 	r.DebugOut.Println("Stop completed")
 }
 
@@ -347,6 +667,8 @@ func (r *Head) Restarts() uint64 {
 // RestartsPerMinute returns the number of restarts for this Head instance in
 // the last minute
 func (r *Head) RestartsPerMinute() uint64 {
+	r.restartsLock.Lock()
+	defer r.restartsLock.Unlock()
 	return cast.ToUint64(r.restartsMin.Count())
 }
 
@@ -355,6 +677,122 @@ func (r *Head) Wait() {
 	r.wg.Wait()
 }
 
+// StopContext is Stop, but blocks until the process has exited and the Head is done
+// with it, or ctx is done. If ctx is done first, its error is returned, and the process
+// may still be exiting.
+func (r *Head) StopContext(ctx context.Context) error {
+	r.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Wait()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stopping %s: %w", r.ID, ctx.Err())
+	}
+}
+
+// finish marks the end of a Run, releasing any pause, and moving the Head to StateStopped
+func (r *Head) finish() {
+	r.unpause()
+
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	r.active = false
+	r.stopAfter()
+	if r.state != StateFailed {
+		r.transition(StateStopped)
+	}
+}
+
+// restartDelay returns the duration to wait before restarting, for the attempt
+func (r *Head) restartDelay(attempt int) time.Duration {
+	if r.RestartPolicy != nil {
+		return r.RestartPolicy.Delay(attempt)
+	}
+	return r.RestartDelay
+}
+
+// countRestart increments the restart counters. The lock prevents a racing Stop()
+// from closing the slippy counter out from under a pending Add().
+func (r *Head) countRestart() {
+	r.restartsLock.Lock()
+	defer r.restartsLock.Unlock()
+	atomic.AddUint64(&r.restarts, 1)
+	r.restartsMin.Add(1)
+}
+
+// pipes returns the read ends of new stdout and stderr pipes for the cmd, the stdin pipe of the
+// cmd, and the write ends, which must be closed once the cmd is started, or closes any it made
+// and returns an error. The output pipes are ours, rather than exec's, so the cmd can be waited
+// on without waiting for everything that inherited them to close them.
+func pipes(cmd *exec.Cmd) (stdout, stderr io.ReadCloser, stdin io.WriteCloser, theirs io.Closer, err error) {
+	if stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("'stdinpipe' %w", err)
+	}
+	outr, outw, err := os.Pipe()
+	if err != nil {
+		stdin.Close()
+		return nil, nil, nil, nil, fmt.Errorf("'stdoutpipe' %w", err)
+	}
+	errr, errw, err := os.Pipe()
+	if err != nil {
+		stdin.Close()
+		outr.Close()
+		outw.Close()
+		return nil, nil, nil, nil, fmt.Errorf("'stderrpipe' %w", err)
+	}
+	cmd.Stdout, cmd.Stderr = outw, errw
+	return outr, errr, stdin, closers{outw, errw}, nil
+}
+
+// closers is an io.Closer for several io.Closers
+type closers []io.Closer
+
+// Close closes all of the closers, returning any errors
+func (c closers) Close() error {
+	var errs []error
+	for _, closer := range c {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// outputDrainTimeout is how long to keep reading the output of a process once it has exited,
+// while anything it left behind, such as a backgrounded child, holds the output open
+var outputDrainTimeout = time.Second
+
+// drainOutput waits for the readers to finish reading the output of an exited process, for up to
+// outputDrainTimeout, and then closes the output, which ends any readers still waiting on it
+func (r *Head) drainOutput(name string, readers *sync.WaitGroup, stdout, stderr io.Closer) {
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		readers.Wait()
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(outputDrainTimeout):
+		r.DebugOut.Printf("%s/%s Output still open after exit, closing it", name, r.ID)
+	}
+	closeOutput(stdout, stderr)
+	<-drained
+}
+
+// closeOutput closes the stdout and stderr pipe ends. stderr is nil with a PTY.
+func closeOutput(stdout, stderr io.Closer) {
+	stdout.Close()
+	if stderr != nil {
+		stderr.Close()
+	}
+}
+
 // errorHandler is an internal regurgitator to asynchronously
 // spew errors down the errorChan
 func (r *Head) errorHandler(e error) {
@@ -13,6 +13,8 @@ import (
 	"context"
 	"errors"
 	"log"
+	"os"
+	"os/exec"
 	"testing"
 	"time"
 )
@@ -66,6 +68,29 @@ func Test_HeadClone(t *testing.T) {
 	})
 }
 
+func Test_HeadCloneSettings(t *testing.T) {
+	errorChan := make(chan error, 1)
+	Convey("When a Head is configured and then Cloned, the clone carries the same settings", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+		r.Autorestart(true)
+		r.SetChildEnv([]string{"THEVAR=WORLD"})
+		r.SetMgInterval(time.Second)
+		r.Values.Store("Name", "sleepy")
+
+		cr := r.Clone()
+		defer cr.Stop()
+
+		So(cr.String(), ShouldEqual, r.String())
+		So(cr.autoRestart.Load(), ShouldEqual, true)
+		So(cr.childEnv, ShouldResemble, []string{"THEVAR=WORLD"})
+		So(cr.mgInterval, ShouldEqual, time.Second)
+		name, ok := cr.Values.Load("Name")
+		So(ok, ShouldBeTrue)
+		So(name, ShouldEqual, "sleepy")
+	})
+}
+
 func Test_HeadStop(t *testing.T) {
 	defer leaktest.Check(t)()
 
@@ -88,6 +113,52 @@ func Test_HeadStop(t *testing.T) {
 	})
 }
 
+func Test_HeadStopSignal(t *testing.T) {
+	defer leaktest.Check(t)()
+
+	errorChan := make(chan error, 1)
+	var buf Sbuffer
+
+	Convey("When a Head Initializes that traps SIGTERM", t, func() {
+		r := BashDashC("trap 'echo bye; exit 0' TERM; while true; do sleep 0.1; done", errorChan)
+		defer r.Stop()
+		r.StdOut = log.New(&buf, "", 0)
+
+		Convey("and a Stop is issued, it gets to clean up before exiting", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			time.Sleep(500 * time.Millisecond)
+			r.Stop()
+			r.Wait()
+			So(buf.String(), ShouldEqual, "bye\n")
+		})
+	})
+}
+
+func Test_HeadStopTimeout(t *testing.T) {
+	defer leaktest.Check(t)()
+
+	errorChan := make(chan error, 1)
+
+	Convey("When a Head Initializes that ignores SIGTERM", t, func() {
+		r := BashDashC("trap '' TERM; while true; do sleep 0.1; done", errorChan)
+		defer r.Stop()
+		r.StopTimeout = 500 * time.Millisecond
+
+		Convey("and a Stop is issued, it is killed after the StopTimeout", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			time.Sleep(500 * time.Millisecond)
+			start := time.Now()
+			r.Stop()
+			r.Wait()
+			diff := time.Since(start)
+			So(diff, ShouldBeGreaterThanOrEqualTo, r.StopTimeout)
+			So(diff, ShouldBeLessThan, 2*time.Second)
+		})
+	})
+}
+
 func Test_HeadTimeout(t *testing.T) {
 
 	errorChan := make(chan error, 1)
@@ -143,7 +214,7 @@ func Test_HeadAutorestart(t *testing.T) {
 	errorChan := make(chan error, 1)
 	//defer close(errorChan)
 	Convey("When a Head Initializes that autorestarts", t, func() {
-		r := New("echo hi", nil, errorChan)
+		r := New("echo", []string{"hi"}, errorChan)
 		defer r.Stop()
 		r.Autorestart(true)
 
@@ -164,7 +235,7 @@ func Test_HeadAutorestartCancel(t *testing.T) {
 	errorChan := make(chan error, 1)
 	//defer close(errorChan)
 	Convey("When a Head Initializes that autorestarts", t, func() {
-		r := New("echo hi", nil, errorChan)
+		r := New("echo", []string{"hi"}, errorChan)
 		defer r.Stop()
 		r.Autorestart(true)
 
@@ -416,6 +487,50 @@ func Test_HeadDashCStdOutTrapPipe(t *testing.T) {
 	})
 }
 
+func Test_HeadOutputHeldOpen(t *testing.T) {
+	errorChan := make(chan error, 10)
+	var buf Sbuffer
+
+	Convey("When a Head's process exits, leaving a backgrounded child holding its output open", t, func() {
+		r := BashDashC("sleep 5 & echo hi", errorChan)
+		defer r.Stop()
+		r.StdOut = log.New(&buf, "", 0)
+
+		start := time.Now()
+		r.Run()
+		r.Wait()
+
+		Convey("its exit is seen, and its output read, without waiting for the child", func() {
+			So(time.Since(start), ShouldBeLessThan, outputDrainTimeout+time.Second)
+			So(buf.String(), ShouldEqual, "hi\n")
+		})
+	})
+}
+
+func Test_pipes(t *testing.T) {
+	Convey("When pipes are made for a command, all three are returned, with the write ends", t, func() {
+		stdout, stderr, stdin, theirs, err := pipes(exec.Command("true"))
+		So(err, ShouldBeNil)
+		So(stdout, ShouldNotBeNil)
+		So(stderr, ShouldNotBeNil)
+		So(stdin, ShouldNotBeNil)
+		So(theirs, ShouldNotBeNil)
+		So(theirs.Close(), ShouldBeNil)
+	})
+
+	Convey("When a pipe can't be made for a command, none are returned, and the error names the pipe", t, func() {
+		cmd := exec.Command("true")
+		cmd.Stdin = os.Stdin
+		stdout, stderr, stdin, theirs, err := pipes(cmd)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "'stdinpipe'")
+		So(stdout, ShouldBeNil)
+		So(stderr, ShouldBeNil)
+		So(stdin, ShouldBeNil)
+		So(theirs, ShouldBeNil)
+	})
+}
+
 func Test_HeadStdErrTrap(t *testing.T) {
 	defer leaktest.Check(t)()
 
@@ -498,3 +613,60 @@ func Test_CopyValues(t *testing.T) {
 
 	})
 }
+
+func Test_HeadRunContext(t *testing.T) {
+	defer leaktest.Check(t)()
+
+	errorChan := make(chan error, 10)
+	Convey("When a Head is run with a context, and the context is cancelled, the Head stops", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := New("sleep", []string{"30"}, errorChan)
+		r.StopTimeout = 0
+
+		So(r.RunContext(ctx), ShouldNotBeZeroValue)
+		time.Sleep(100 * time.Millisecond)
+		So(r.State(), ShouldEqual, StateRunning)
+
+		cancel()
+		r.Wait()
+		So(r.State(), ShouldEqual, StateStopped)
+		So(r.History()[0].Reason, ShouldEqual, ExitStopped)
+	})
+
+	Convey("When a Head is run with a context, and finishes on its own, it is done with the context", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := New("true", []string{}, errorChan)
+		defer r.Stop()
+
+		r.RunContext(ctx)
+		r.Wait()
+		So(r.State(), ShouldEqual, StateStopped)
+	})
+}
+
+func Test_HeadStopContext(t *testing.T) {
+	errorChan := make(chan error, 10)
+	Convey("When a Head is running a process that is slow to stop", t, func() {
+		r := BashDashC("trap 'sleep 0.5; exit 0' TERM; while true; do sleep 0.05; done", errorChan)
+		r.Run()
+		time.Sleep(200 * time.Millisecond)
+
+		Convey("and it is stopped with a short deadline, the deadline is reported", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := r.StopContext(ctx)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			So(r.State(), ShouldEqual, StateStopping)
+
+			Convey("and when it is stopped again with a longer deadline, it finishes", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+
+				So(r.StopContext(ctx), ShouldBeNil)
+				So(r.State(), ShouldEqual, StateStopped)
+			})
+		})
+	})
+}
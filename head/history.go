@@ -0,0 +1,126 @@
+package head
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/cognusion/prochydra/athena"
+)
+
+// ExitReason describes why a run of a process ended
+type ExitReason string
+
+// ExitReasons
+const (
+	// ExitNormal is a process that exited on its own, successfully or not
+	ExitNormal = ExitReason("exited")
+	// ExitStopped is a process that was stopped via Stop()
+	ExitStopped = ExitReason("stopped")
+	// ExitTimeout is a process that was stopped because it exceeded the Timeout
+	ExitTimeout = ExitReason("timeout")
+	// ExitMemory is a process that was killed by the memory guard for exceeding MaxPSS
+	ExitMemory = ExitReason("memory")
+	// ExitLiveness is a process that was killed for failing its LiveProbe
+	ExitLiveness = ExitReason("liveness")
+	// ExitTriggered is a process that was killed by a Trigger with TriggerRestart
+	ExitTriggered = ExitReason("triggered")
+	// ExitStartFailed is a process that could not be started
+	ExitStartFailed = ExitReason("startfailed")
+)
+
+// RunRecord is the post-mortem of a single run of a process
+type RunRecord struct {
+	// Name is the instance name of the Head's run
+	Name string
+	// PID is the process ID, or 0 if the process never started
+	PID int
+	// Start is when the run began
+	Start time.Time
+	// End is when the run ended
+	End time.Time
+	// ExitCode is the exit code of the process, or -1 if it was signalled or never started
+	ExitCode int
+	// Signal is the signal that terminated the process, or nil
+	Signal os.Signal
+	// Reason is why the run ended
+	Reason ExitReason
+}
+
+// String returns the RunRecord in a line
+func (rr RunRecord) String() string {
+	sig := "none"
+	if rr.Signal != nil {
+		sig = rr.Signal.String()
+	}
+	return fmt.Sprintf("%s %s pid=%d reason=%s exit=%d signal=%s runtime=%s",
+		rr.Start.Format(time.RFC3339), rr.Name, rr.PID, rr.Reason, rr.ExitCode, sig, rr.End.Sub(rr.Start).Round(time.Millisecond))
+}
+
+// History returns the RunRecords of the most recent runs of this Head, oldest first,
+// up to HistorySize.
+func (r *Head) History() []RunRecord {
+	r.historyLock.Lock()
+	defer r.historyLock.Unlock()
+
+	h := make([]RunRecord, len(r.history))
+	copy(h, r.history)
+	return h
+}
+
+// record adds a RunRecord for the cmd to the history, dropping the oldest if
+// HistorySize is exceeded, and returns it.
+func (r *Head) record(name string, cmd *exec.Cmd, start time.Time, reason ExitReason) RunRecord {
+	rr := RunRecord{
+		Name:     name,
+		Start:    start,
+		End:      time.Now(),
+		ExitCode: -1,
+		Reason:   reason,
+	}
+	if cmd.Process != nil {
+		rr.PID = cmd.Process.Pid
+	}
+	if cmd.ProcessState != nil {
+		rr.ExitCode = cmd.ProcessState.ExitCode()
+		if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			rr.Signal = ws.Signal()
+		}
+	}
+
+	r.historyLock.Lock()
+	defer r.historyLock.Unlock()
+
+	r.history = append(r.history, rr)
+	if r.HistorySize >= 0 && len(r.history) > r.HistorySize {
+		r.history = r.history[len(r.history)-r.HistorySize:]
+	}
+	return rr
+}
+
+// exitReason returns the ExitReason for a process that started and has been waited on,
+// given the cause of its local context, if any
+func (r *Head) exitReason(cause error, mg *athena.MemoryGuard) ExitReason {
+	if r.ctx.Err() != nil {
+		return ExitStopped
+	} else if errors.Is(cause, context.DeadlineExceeded) {
+		return ExitTimeout
+	} else if errors.Is(cause, ErrLivenessFailed) {
+		return ExitLiveness
+	} else if errors.Is(cause, ErrTriggered) {
+		return ExitTriggered
+	}
+
+	if mg != nil {
+		select {
+		case <-mg.KillChan:
+			return ExitMemory
+		default:
+		}
+	}
+	return ExitNormal
+}
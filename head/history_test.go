@@ -0,0 +1,118 @@
+package head
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_HeadHistory(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head runs a command that fails", t, func() {
+		r := New("false", nil, errorChan)
+		defer r.Stop()
+
+		Convey("the History has one record of a normal exit, with the exit code", func() {
+			start := time.Now()
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+
+			h := r.History()
+			So(h, ShouldHaveLength, 1)
+			So(h[0].Reason, ShouldEqual, ExitNormal)
+			So(h[0].ExitCode, ShouldEqual, 1)
+			So(h[0].Signal, ShouldBeNil)
+			So(h[0].PID, ShouldBeGreaterThan, 0)
+			So(h[0].Name, ShouldNotBeZeroValue)
+			So(h[0].Start, ShouldHappenOnOrAfter, start)
+			So(h[0].End, ShouldHappenOnOrAfter, h[0].Start)
+			So(h[0].String(), ShouldContainSubstring, "reason=exited exit=1 signal=none")
+		})
+	})
+
+	Convey("When a Head is stopped", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+
+		Convey("the History records it as stopped, with the signal", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			time.Sleep(100 * time.Millisecond)
+			r.Stop()
+			r.Wait()
+
+			h := r.History()
+			So(h, ShouldHaveLength, 1)
+			So(h[0].Reason, ShouldEqual, ExitStopped)
+			So(h[0].ExitCode, ShouldEqual, -1)
+			So(h[0].Signal, ShouldEqual, syscall.SIGTERM)
+		})
+	})
+
+	Convey("When a Head times out", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+		r.Timeout = 100 * time.Millisecond
+
+		Convey("the History records it as a timeout", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+
+			h := r.History()
+			So(h, ShouldHaveLength, 1)
+			So(h[0].Reason, ShouldEqual, ExitTimeout)
+		})
+	})
+
+	Convey("When a Head cannot start", t, func() {
+		r := New("/this/does/not/exist", nil, errorChan)
+		defer r.Stop()
+
+		Convey("the History records it as a start failure", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+
+			h := r.History()
+			So(h, ShouldHaveLength, 1)
+			So(h[0].Reason, ShouldEqual, ExitStartFailed)
+			So(h[0].PID, ShouldEqual, 0)
+		})
+	})
+
+	Convey("When a Head autorestarts many times", t, func() {
+		r := New("true", nil, errorChan)
+		defer r.Stop()
+		r.Autorestart(true)
+		r.HistorySize = 3
+
+		Convey("the History is bounded by HistorySize", func() {
+			time.AfterFunc(500*time.Millisecond, func() { r.Stop() })
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+
+			So(r.Restarts(), ShouldBeGreaterThan, 3)
+			So(r.History(), ShouldHaveLength, 3)
+		})
+
+		Convey("a negative HistorySize keeps all of it, and 0 none of it", func() {
+			r.HistorySize = -1
+			time.AfterFunc(500*time.Millisecond, func() { r.Stop() })
+			r.Run()
+			r.Wait()
+
+			So(len(r.History()), ShouldBeGreaterThan, 3)
+
+			r.HistorySize = 0
+			r.record("name", &exec.Cmd{}, time.Now(), ExitNormal)
+			So(r.History(), ShouldBeEmpty)
+		})
+	})
+}
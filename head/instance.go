@@ -0,0 +1,107 @@
+package head
+
+import (
+	"fmt"
+	"time"
+)
+
+// Instance is a snapshot of the process a Head is running, or last ran
+type Instance struct {
+	// Name is the instance name of the Head's run
+	Name string
+	// PID is the process ID, or 0 if there isn't one (yet)
+	PID int
+	// Start is when the process started
+	Start time.Time
+	// State is the State of the Head
+	State State
+}
+
+// Uptime returns how long the process has been running, or 0 if it isn't
+func (i Instance) Uptime() time.Duration {
+	if !i.running() && i.State != StatePaused {
+		return 0
+	}
+	return time.Since(i.Start)
+}
+
+// running returns true if the process is running, and isn't paused, whether or not it is
+// ready yet
+func (i Instance) running() bool {
+	return i.State.running() || (i.State == StateStarting && i.PID > 0)
+}
+
+// String returns the Instance in a line
+func (i Instance) String() string {
+	return fmt.Sprintf("%s pid=%d state=%s uptime=%s", i.Name, i.PID, i.State, i.Uptime().Round(time.Second))
+}
+
+// Instance returns a snapshot of the process this Head is running, or last ran
+func (r *Head) Instance() Instance {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	i := r.instance
+	i.State = r.state
+	return i
+}
+
+// setInstance updates the Instance, and moves the Head to the State
+func (r *Head) setInstance(name string, pid int, state State) {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	if pid > 0 {
+		r.instance.Start = time.Now()
+	}
+	r.instance.Name = name
+	r.instance.PID = pid
+	r.transition(state)
+}
+
+// pauseInstance moves a running Head to StatePaused, remembering its State for
+// resumeInstance. It returns false if the Head isn't running.
+func (r *Head) pauseInstance() bool {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	if i := (Instance{PID: r.instance.PID, State: r.state}); !i.running() {
+		return false
+	}
+	r.resumeState = r.state
+	return r.transition(StatePaused)
+}
+
+// resumeInstance moves a paused Head back to its State before pauseInstance, or the
+// State it was moved to while paused. It returns false if the Head isn't paused, and
+// the State resumed to.
+func (r *Head) resumeInstance() (bool, State) {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	if r.state != StatePaused {
+		return false, r.state
+	}
+	return r.transition(r.resumeState), r.state
+}
+
+// readyInstance moves the running or starting Head of the pid to StateReady, or arranges
+// for it to resume as ready if paused. It returns false if the pid isn't running.
+func (r *Head) readyInstance(pid int) bool {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	if r.instance.PID != pid {
+		return false
+	}
+	switch r.state {
+	case StateStarting, StateRunning:
+		return r.transition(StateReady)
+	case StatePaused:
+		if r.resumeState == StateStarting || r.resumeState == StateRunning {
+			r.resumeState = StateReady
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package head
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_HeadInstance(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head has never run, its Instance is empty", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+
+		i := r.Instance()
+		So(i.State, ShouldEqual, StateInit)
+		So(i.PID, ShouldEqual, 0)
+		So(i.Uptime(), ShouldEqual, 0)
+	})
+
+	Convey("When a Head is running", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(200 * time.Millisecond)
+
+		Convey("its Instance has the live process' details", func() {
+			i := r.Instance()
+			So(i.Name, ShouldNotBeZeroValue)
+			So(i.State, ShouldEqual, StateRunning)
+			So(i.PID, ShouldBeGreaterThan, 0)
+			So(syscall.Kill(i.PID, 0), ShouldBeNil)
+			So(i.Uptime(), ShouldBeGreaterThanOrEqualTo, 100*time.Millisecond)
+			So(i.String(), ShouldContainSubstring, "state=running")
+
+			Convey("and when it is stopped, the Instance has exited", func() {
+				r.Stop()
+				r.Wait()
+
+				e := r.Instance()
+				So(e.Name, ShouldEqual, i.Name)
+				So(e.PID, ShouldEqual, i.PID)
+				So(e.State, ShouldEqual, StateStopped)
+				So(e.Uptime(), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,263 @@
+package head
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lineTimeFormat is how the time of a Line is formatted for text
+const lineTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Stream identifies which output of a process a Line came from
+type Stream string
+
+// Streams
+const (
+	StreamStdout = Stream("stdout")
+	StreamStderr = Stream("stderr")
+)
+
+// ToStream returns the Stream of the string, or an error if it is neither "stdout" nor "stderr"
+func ToStream(s string) (Stream, error) {
+	switch Stream(s) {
+	case StreamStdout, StreamStderr:
+		return Stream(s), nil
+	}
+	return "", fmt.Errorf("unknown stream '%s'", s)
+}
+
+// Line is a line of output from a process, without its newline
+type Line struct {
+	// Time is when the line was read
+	Time time.Time
+	// Stream is the output the line was read from
+	Stream Stream
+	// Bytes is the line, or a 64KiB piece of a longer one. It belongs to the Line,
+	// and must not be modified
+	Bytes []byte
+	// HeadID is the ID of the Head running the process
+	HeadID string
+	// Name is the instance name of the Head's run
+	Name string
+	// PID is the process ID
+	PID int
+	// Run is the number of the Head's run, starting at 1
+	Run uint64
+	// Piece is the number of this piece of a line longer than 64KiB, starting at 0
+	Piece int
+	// Partial is true if the line is longer than 64KiB, and continues in the next Line
+	Partial bool
+}
+
+// String returns the Line with its time and stream
+func (l Line) String() string {
+	return fmt.Sprintf("%s %s %s", l.Time.Format(lineTimeFormat), l.Stream, l.Bytes)
+}
+
+// ExpandPrefix returns the prefix template with its macros replaced by those of the Line:
+// {headid}, {instance}, {pid}, {run}, {stream} and {time}.
+func ExpandPrefix(prefix string, l Line) string {
+	if !strings.Contains(prefix, "{") {
+		return prefix
+	}
+	return strings.NewReplacer(
+		"{headid}", l.HeadID,
+		"{instance}", l.Name,
+		"{pid}", strconv.Itoa(l.PID),
+		"{run}", strconv.FormatUint(l.Run, 10),
+		"{stream}", string(l.Stream),
+		"{time}", l.Time.Format(lineTimeFormat),
+	).Replace(prefix)
+}
+
+// OutputSink receives the Lines a Head's processes output. Output is called from the goro
+// reading each stream, so may be called concurrently for stdout and stderr, and a slow
+// OutputSink holds up the process.
+type OutputSink interface {
+	Output(Line)
+}
+
+// OutputFunc is a func that is an OutputSink
+type OutputFunc func(Line)
+
+// Output calls f(l)
+func (f OutputFunc) Output(l Line) {
+	f(l)
+}
+
+// LogSink is an OutputSink that prints the bytes of each Line to a log.Logger, Out for
+// stdout and Err for stderr. If Err is nil, stderr is printed to Out too. Empty lines
+// are not printed. A line that comes in pieces is printed whole, with the logger's header
+// and the Prefix before the first piece only.
+type LogSink struct {
+	Out *log.Logger
+	Err *log.Logger
+	// Prefix, if set, is expanded by ExpandPrefix and printed before each line
+	Prefix string
+}
+
+// Output prints the Line to the log.Logger of its Stream
+func (s LogSink) Output(l Line) {
+	if len(l.Bytes) == 0 {
+		return
+	}
+	out := s.Out
+	if l.Stream == StreamStderr && s.Err != nil {
+		out = s.Err
+	}
+	if out == nil {
+		return
+	} else if l.Piece == 0 && !l.Partial {
+		out.Printf("%s%s\n", ExpandPrefix(s.Prefix, l), l.Bytes)
+		return
+	}
+
+	// Printf would end each piece with a newline, so write them to the logger's Writer
+	var b []byte
+	if l.Piece == 0 {
+		b = append(logHeader(out), ExpandPrefix(s.Prefix, l)...)
+	}
+	b = append(b, l.Bytes...)
+	if !l.Partial {
+		b = append(b, '\n')
+	}
+	out.Writer().Write(b)
+}
+
+// logHeader returns what the log.Logger prints before each message: its prefix, and the
+// date and time, as its flags say
+func logHeader(out *log.Logger) []byte {
+	var buf bytes.Buffer
+	log.New(&buf, out.Prefix(), out.Flags()).Print()
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// Attach adds the OutputSink to those receiving the Lines of the Head's running process, and
+// of any later runs, until the returned func is called to detach it.
+func (r *Head) Attach(s OutputSink) (detach func()) {
+	r.attachLock.Lock()
+	defer r.attachLock.Unlock()
+
+	if r.attached == nil {
+		r.attached = make(map[uint64]OutputSink)
+	}
+	r.attachSeq++
+	id := r.attachSeq
+	r.attached[id] = s
+
+	return func() {
+		r.attachLock.Lock()
+		defer r.attachLock.Unlock()
+		delete(r.attached, id)
+	}
+}
+
+// attachedSinks returns the OutputSinks currently Attached
+func (r *Head) attachedSinks() []OutputSink {
+	r.attachLock.Lock()
+	defer r.attachLock.Unlock()
+
+	if len(r.attached) == 0 {
+		return nil
+	}
+	sinks := make([]OutputSink, 0, len(r.attached))
+	for _, s := range r.attached {
+		sinks = append(sinks, s)
+	}
+	return sinks
+}
+
+// runOutput makes the Lines of a run of a process, and hands them to the Head's
+// OutputSinks
+type runOutput struct {
+	r        *Head
+	name     string
+	run      uint64
+	pid      int
+	running  chan struct{} // closed once pid is set, or the process failed to start
+	once     sync.Once
+	sinks    []OutputSink
+	triggers *triggerRun
+	onLine   func([]byte)
+}
+
+// newRunOutput returns a runOutput for the run, that hands each line to StdOut or StdErr, and
+// the OutputSinks, keeps it for Tail, checks it against the triggers, if any, and then hands
+// it to onLine, if set
+func (r *Head) newRunOutput(name string, run uint64, onLine func([]byte), triggers *triggerRun) *runOutput {
+	sinks := make([]OutputSink, 0, len(r.OutputSinks)+1)
+	sinks = append(sinks, LogSink{Out: r.StdOut, Err: r.StdErr, Prefix: r.OutputPrefix})
+	sinks = append(sinks, r.OutputSinks...)
+	return &runOutput{
+		r:        r,
+		name:     name,
+		run:      run,
+		running:  make(chan struct{}),
+		sinks:    sinks,
+		triggers: triggers,
+		onLine:   onLine,
+	}
+}
+
+// started records the PID of the run, for its Lines and Triggers
+func (o *runOutput) started(pid int) {
+	o.once.Do(func() {
+		o.pid = pid
+		close(o.running)
+	})
+	if o.triggers != nil {
+		o.triggers.started(pid)
+	}
+}
+
+// exited records that the run's process is gone, or never started, so Triggers
+// don't signal whatever has its PID now
+func (o *runOutput) exited() {
+	o.once.Do(func() { close(o.running) })
+	if o.triggers != nil {
+		o.triggers.exited()
+	}
+}
+
+// hook returns an onLine for readLines of the Stream
+func (o *runOutput) hook(stream Stream) func([]byte, bool) {
+	var piece int // of a line handed on in pieces
+	return func(b []byte, partial bool) {
+		// The process can write before Start returns with its PID
+		<-o.running
+		l := Line{
+			Time:    time.Now(),
+			Stream:  stream,
+			Bytes:   append([]byte(nil), b...),
+			HeadID:  o.r.ID,
+			Name:    o.name,
+			PID:     o.pid,
+			Run:     o.run,
+			Piece:   piece,
+			Partial: partial,
+		}
+		if partial {
+			piece++
+		} else {
+			piece = 0
+		}
+		for _, s := range o.sinks {
+			s.Output(l)
+		}
+		for _, s := range o.r.attachedSinks() {
+			s.Output(l)
+		}
+		o.r.tail(l)
+		if o.triggers != nil {
+			o.triggers.check(l)
+		}
+		if o.onLine != nil {
+			o.onLine(b)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package head
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// lineSink is an OutputSink that keeps the Lines it receives
+type lineSink struct {
+	lines []Line
+	lock  sync.Mutex
+}
+
+func (s *lineSink) Output(l Line) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lines = append(s.lines, l)
+}
+
+func (s *lineSink) Lines() []Line {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Line(nil), s.lines...)
+}
+
+func Test_LogSink(t *testing.T) {
+	Convey("When a LogSink has Out and Err, each Line is printed to the logger of its stream, without empty lines", t, func() {
+		var out, err bytes.Buffer
+		s := LogSink{Out: log.New(&out, "", 0), Err: log.New(&err, "", 0)}
+		s.Output(Line{Stream: StreamStdout, Bytes: []byte("one")})
+		s.Output(Line{Stream: StreamStdout, Bytes: []byte{}})
+		s.Output(Line{Stream: StreamStderr, Bytes: []byte("two")})
+		So(out.String(), ShouldEqual, "one\n")
+		So(err.String(), ShouldEqual, "two\n")
+	})
+
+	Convey("When a LogSink has only Out, both streams are printed to it", t, func() {
+		var out bytes.Buffer
+		s := LogSink{Out: log.New(&out, "", 0)}
+		s.Output(Line{Stream: StreamStdout, Bytes: []byte("one")})
+		s.Output(Line{Stream: StreamStderr, Bytes: []byte("two")})
+		So(out.String(), ShouldEqual, "one\ntwo\n")
+	})
+
+	Convey("When a LogSink gets a line in pieces, it prints the header and Prefix before the first, and the newline after the last", t, func() {
+		var out bytes.Buffer
+		s := LogSink{Out: log.New(&out, "log: ", 0), Prefix: "{stream} "}
+		s.Output(Line{Stream: StreamStdout, Bytes: []byte("one"), Partial: true})
+		s.Output(Line{Stream: StreamStdout, Bytes: []byte("two"), Piece: 1, Partial: true})
+		s.Output(Line{Stream: StreamStdout, Bytes: []byte("three"), Piece: 2})
+		s.Output(Line{Stream: StreamStdout, Bytes: []byte("four")})
+		So(out.String(), ShouldEqual, "log: stdout onetwothree\nlog: stdout four\n")
+	})
+}
+
+func Test_ExpandPrefix(t *testing.T) {
+	Convey("When a prefix is expanded for a Line, its macros are replaced", t, func() {
+		l := Line{
+			Time:   time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC),
+			Stream: StreamStderr,
+			HeadID: "xyz",
+			Name:   "Happy Camper",
+			PID:    123,
+			Run:    4,
+		}
+		So(ExpandPrefix("", l), ShouldEqual, "")
+		So(ExpandPrefix("> ", l), ShouldEqual, "> ")
+		So(ExpandPrefix("[{headid}/{instance}] ", l), ShouldEqual, "[xyz/Happy Camper] ")
+		So(ExpandPrefix("{time} {stream} {pid}#{run} {unknown} ", l), ShouldEqual, "2020-01-02T03:04:05.006Z stderr 123#4 {unknown} ")
+	})
+
+	Convey("When a LogSink has a Prefix, it is expanded before each line", t, func() {
+		var out bytes.Buffer
+		s := LogSink{Out: log.New(&out, "", 0), Prefix: "[{instance}:{stream}] "}
+		s.Output(Line{Stream: StreamStdout, Name: "a", Bytes: []byte("one")})
+		s.Output(Line{Stream: StreamStderr, Name: "a", Bytes: []byte("two")})
+		So(out.String(), ShouldEqual, "[a:stdout] one\n[a:stderr] two\n")
+	})
+}
+
+func Test_HeadOutputPrefix(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head with an OutputPrefix outputs a line longer than the read buffer", t, func() {
+		var out Sbuffer
+		r := BashDashC("printf 'x%.0s' $(seq 1 10000); echo; echo short", errorChan)
+		r.ID = "xyz"
+		r.StdOut = log.New(&out, "", 0)
+		r.OutputPrefix = "[{headid}] "
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+
+		Convey("each line is prefixed once, at its start", func() {
+			So(out.String(), ShouldEqual, "[xyz] "+strings.Repeat("x", 10000)+"\n[xyz] short\n")
+		})
+	})
+
+	Convey("When a Head with an OutputPrefix outputs a line longer than 64KiB", t, func() {
+		var out Sbuffer
+		r := BashDashC("head -c 200000 /dev/zero | tr '\\0' x; echo; echo short", errorChan)
+		r.ID = "xyz"
+		r.StdOut = log.New(&out, "log: ", 0)
+		r.OutputPrefix = "[{headid}] "
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+
+		Convey("it is printed whole, with the logger's prefix and the OutputPrefix once, at its start", func() {
+			So(out.String(), ShouldEqual, "log: [xyz] "+strings.Repeat("x", 200000)+"\nlog: [xyz] short\n")
+		})
+	})
+}
+
+func Test_HeadAttach(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When an OutputSink is Attached to a running Head", t, func() {
+		var sink lineSink
+		r := BashDashC("while read l; do echo $l; done", errorChan)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		detach := r.Attach(&sink)
+
+		Convey("it gets the Lines output until it is detached", func() {
+			r.Write([]byte("one\n"))
+			time.Sleep(100 * time.Millisecond)
+			detach()
+			r.Write([]byte("two\n"))
+			time.Sleep(100 * time.Millisecond)
+
+			So(lineStrings(sink.Lines()), ShouldResemble, []string{"one"})
+			So(lineStrings(r.Tail(-1, "")), ShouldResemble, []string{"one", "two"})
+		})
+	})
+}
+
+func Test_HeadOutputSinks(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head with two OutputSinks and a StdOut logger runs twice", t, func() {
+		var (
+			out   Sbuffer
+			one   lineSink
+			two   lineSink
+			funcs atomic.Int64
+		)
+		r := BashDashC("echo hello; echo world >&2", errorChan)
+		r.ID = "xyz"
+		r.StdOut = log.New(&out, "", 0)
+		r.OutputSinks = []OutputSink{&one, &two, OutputFunc(func(Line) { funcs.Add(1) })}
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+		r.Run()
+		r.Wait()
+
+		Convey("each sink gets every Line, with where it came from", func() {
+			lines := one.Lines()
+			So(lines, ShouldHaveLength, 4)
+			So(two.Lines(), ShouldHaveLength, 4)
+			So(funcs.Load(), ShouldEqual, 4)
+
+			runs := map[uint64]int{}
+			for _, l := range lines {
+				So(l.HeadID, ShouldEqual, "xyz")
+				So(l.Name, ShouldNotBeEmpty)
+				So(l.PID, ShouldBeGreaterThan, 0)
+				So(l.Time, ShouldNotBeZeroValue)
+				runs[l.Run]++
+				if l.Stream == StreamStdout {
+					So(string(l.Bytes), ShouldEqual, "hello")
+				} else {
+					So(l.Stream, ShouldEqual, StreamStderr)
+					So(string(l.Bytes), ShouldEqual, "world")
+				}
+			}
+			So(runs, ShouldResemble, map[uint64]int{1: 2, 2: 2})
+			So(lines[0].PID, ShouldEqual, r.History()[lines[0].Run-1].PID)
+		})
+
+		Convey("and the StdOut logger still gets stdout", func() {
+			So(out.String(), ShouldEqual, "hello\nhello\n")
+		})
+	})
+}
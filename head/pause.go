@@ -0,0 +1,158 @@
+package head
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cognusion/prochydra/athena"
+)
+
+// Pause freezes the running process (or its process group, if ProcessGroup) with SIGSTOP.
+// While paused, the Timeout and memory guard are held, and the Head will not be
+// restarted if the process exits, until Resume is called.
+func (r *Head) Pause() error {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	if r.paused != nil {
+		return fmt.Errorf("already paused")
+	}
+
+	if !r.pauseInstance() {
+		return fmt.Errorf("no running process to pause")
+	}
+
+	i := r.Instance()
+	if err := r.sendSignal(i.PID, syscall.SIGSTOP); err != nil {
+		r.resumeInstance()
+		return err
+	}
+	r.paused = make(chan struct{})
+	if r.runTimer != nil {
+		r.runTimer.Pause()
+	}
+	if r.runGuard != nil {
+		r.runGuard.Pause()
+	}
+	r.emit(Event{Type: EventPaused, Name: i.Name, PID: i.PID, ExitCode: -1})
+	return nil
+}
+
+// Resume continues a process frozen by Pause with SIGCONT, and releases any held
+// Timeout, memory guard and restarts.
+func (r *Head) Resume() error {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	if r.paused == nil {
+		return fmt.Errorf("not paused")
+	}
+	close(r.paused)
+	r.paused = nil
+
+	if ok, state := r.resumeInstance(); !ok || (!state.running() && state != StateStarting) {
+		// It exited while paused, so there's nothing to continue. It was paused with
+		// its process running, so starting is a process that isn't ready yet.
+		return nil
+	}
+
+	if r.runTimer != nil {
+		r.runTimer.Resume()
+	}
+	if r.runGuard != nil {
+		r.runGuard.Resume()
+	}
+	i := r.Instance()
+	r.emit(Event{Type: EventResumed, Name: i.Name, PID: i.PID, ExitCode: -1})
+	return r.sendSignal(i.PID, syscall.SIGCONT)
+}
+
+// Paused returns true if the Head is paused
+func (r *Head) Paused() bool {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+	return r.paused != nil
+}
+
+// unpause releases a pause without continuing the process, for when it has exited
+func (r *Head) unpause() {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	if r.paused != nil {
+		close(r.paused)
+		r.paused = nil
+	}
+}
+
+// waitResumed blocks until the Head is not paused, or is stopped
+func (r *Head) waitResumed() {
+	r.pauseLock.Lock()
+	paused := r.paused
+	r.pauseLock.Unlock()
+
+	if paused != nil {
+		select {
+		case <-paused:
+		case <-r.ctx.Done():
+		}
+	}
+}
+
+// setRun sets the Timeout timer and memory guard of the current run, so they may be
+// held while paused. Either may be nil.
+func (r *Head) setRun(timer *pauseTimer, mg *athena.MemoryGuard) {
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+	r.runTimer = timer
+	r.runGuard = mg
+}
+
+// pauseTimer is a one-shot timer that can be paused and resumed, so that a Timeout
+// doesn't count the time a process spends paused.
+type pauseTimer struct {
+	f         func()
+	timer     *time.Timer
+	deadline  time.Time
+	remaining time.Duration
+	lock      sync.Mutex
+}
+
+// newPauseTimer returns a running pauseTimer that calls f after d
+func newPauseTimer(d time.Duration, f func()) *pauseTimer {
+	return &pauseTimer{
+		f:        f,
+		timer:    time.AfterFunc(d, f),
+		deadline: time.Now().Add(d),
+	}
+}
+
+// Pause stops the timer, remembering how much time it had left
+func (t *pauseTimer) Pause() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.timer.Stop() {
+		t.remaining = time.Until(t.deadline)
+	}
+}
+
+// Resume restarts a paused timer with the time it had left
+func (t *pauseTimer) Resume() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.remaining > 0 {
+		t.deadline = time.Now().Add(t.remaining)
+		t.timer = time.AfterFunc(t.remaining, t.f)
+		t.remaining = 0
+	}
+}
+
+// Stop stops the timer for good
+func (t *pauseTimer) Stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.timer.Stop()
+	t.remaining = 0
+}
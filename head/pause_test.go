@@ -0,0 +1,150 @@
+package head
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_HeadPause(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head has no running process, it can't be paused or resumed", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+
+		So(r.Pause(), ShouldNotBeNil)
+		So(r.Resume(), ShouldNotBeNil)
+		So(r.Paused(), ShouldBeFalse)
+	})
+
+	Convey("When a Head is running, and is paused", t, func() {
+		events := make(chan Event, 10)
+		r := New("sleep", []string{"30"}, errorChan)
+		r.Subscribe(events)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(200 * time.Millisecond)
+		So(r.Pause(), ShouldBeNil)
+
+		Convey("the process is stopped", func() {
+			i := r.Instance()
+			So(r.Paused(), ShouldBeTrue)
+			So(i.State, ShouldEqual, StatePaused)
+			So(procStateBecomes(i.PID, "T"), ShouldBeTrue)
+			So(r.Pause(), ShouldNotBeNil)
+
+			Convey("and when it is resumed, the process continues", func() {
+				So(r.Resume(), ShouldBeNil)
+				So(r.Paused(), ShouldBeFalse)
+				So(r.Instance().State, ShouldEqual, StateRunning)
+				So(procStateBecomes(i.PID, "S"), ShouldBeTrue)
+				So(eventTypes(events), ShouldContain, EventResumed)
+			})
+
+			Convey("and when it is stopped, the process exits", func() {
+				r.Stop()
+				r.Wait()
+				So(r.Paused(), ShouldBeFalse)
+				So(r.Instance().State, ShouldEqual, StateStopped)
+			})
+		})
+	})
+
+	Convey("When a Head with a Timeout is paused", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		r.Timeout = 300 * time.Millisecond
+		r.StopTimeout = 0
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		So(r.Pause(), ShouldBeNil)
+
+		Convey("the paused time doesn't count against the Timeout", func() {
+			time.Sleep(400 * time.Millisecond)
+			So(r.Instance().State, ShouldEqual, StatePaused)
+
+			So(r.Resume(), ShouldBeNil)
+			r.Wait()
+			h := r.History()
+			So(h, ShouldHaveLength, 1)
+			So(h[0].Reason, ShouldEqual, ExitTimeout)
+			So(h[0].End.Sub(h[0].Start), ShouldBeGreaterThanOrEqualTo, 700*time.Millisecond)
+		})
+	})
+
+	Convey("When an autorestarting Head exits while paused", t, func() {
+		r := BashDashC("sleep 0.3", errorChan)
+		r.Autorestart(true)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		So(r.Pause(), ShouldBeNil)
+		pid := r.Instance().PID
+		So(r.sendSignal(pid, 9), ShouldBeNil) // SIGKILL is honored while stopped
+
+		Convey("it isn't restarted until resumed", func() {
+			time.Sleep(300 * time.Millisecond)
+			So(r.Instance().State, ShouldEqual, StatePaused)
+			So(r.Restarts(), ShouldEqual, 0)
+
+			So(r.Resume(), ShouldBeNil)
+			time.Sleep(200 * time.Millisecond)
+			So(r.Restarts(), ShouldEqual, 1)
+			So(r.Instance().PID, ShouldNotEqual, pid)
+		})
+	})
+}
+
+func Test_PauseTimer(t *testing.T) {
+	Convey("When a pauseTimer is paused, it doesn't fire until resumed", t, func() {
+		fired := make(chan struct{})
+		pt := newPauseTimer(100*time.Millisecond, func() { close(fired) })
+		defer pt.Stop()
+
+		time.Sleep(50 * time.Millisecond)
+		pt.Pause()
+
+		select {
+		case <-fired:
+			So("fired while paused", ShouldBeEmpty)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		start := time.Now()
+		pt.Resume()
+		<-fired
+		So(time.Since(start), ShouldBeLessThan, 100*time.Millisecond)
+	})
+}
+
+// procStateBecomes returns true if the pid is in the state within a second
+func procStateBecomes(pid int, state string) bool {
+	for range 10 {
+		if procState(pid) == state {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+// procState returns the state letter of the pid from /proc, or "" if it doesn't exist
+func procState(pid int) string {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(b[strings.LastIndexByte(string(b), ')')+1:]))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,159 @@
+package head
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoStdin is returned writing to a Head that has never started a process
+var ErrNoStdin = errors.New("no stdin")
+
+// PipePolicy is what a Pipe does with a Line when its buffer is full
+type PipePolicy string
+
+// PipePolicies
+const (
+	// PipeDropOldest drops the oldest buffered Line, to buffer the new one
+	PipeDropOldest = PipePolicy("drop-oldest")
+	// PipeDropNewest drops the new Line
+	PipeDropNewest = PipePolicy("drop-newest")
+	// PipeBlock holds up the producing process until there is room
+	PipeBlock = PipePolicy("block")
+)
+
+// ToPipePolicy returns the PipePolicy of the string, or an error if it is not known
+func ToPipePolicy(s string) (PipePolicy, error) {
+	switch p := PipePolicy(s); p {
+	case PipeDropOldest, PipeDropNewest, PipeBlock:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown pipe policy '%s'", s)
+}
+
+// Pipe is an OutputSink that writes the Lines of a Stream, each with a newline, to the stdin
+// of another Head's process, whichever run that is. Lines are buffered while the consumer
+// isn't taking them, e.g. between restarts, up to Size, after which the Policy applies. Lines
+// written to a consumer that exits before reading them are lost.
+type Pipe struct {
+	// To is the consuming Head
+	To *Head
+	// Stream is the output piped, or both if ""
+	Stream Stream
+	// Size is the number of Lines buffered
+	Size int
+	// Policy is what happens to a Line when the buffer is full
+	Policy PipePolicy
+	// RetryInterval is the duration between attempts to write to a consumer that isn't
+	// taking Lines
+	RetryInterval time.Duration
+
+	queue   [][]byte
+	dropped atomic.Uint64
+	closed  bool
+	lock    sync.Mutex
+	cond    *sync.Cond
+	once    sync.Once
+}
+
+// NewPipe returns a Pipe of the Stream to the Head, buffering size Lines under the PipePolicy.
+// It should be added to the OutputSinks of the producing Head, and Closed when no longer needed.
+func NewPipe(to *Head, stream Stream, size int, policy PipePolicy) *Pipe {
+	p := &Pipe{
+		To:            to,
+		Stream:        stream,
+		Size:          size,
+		Policy:        policy,
+		RetryInterval: 100 * time.Millisecond,
+	}
+	p.cond = sync.NewCond(&p.lock)
+	return p
+}
+
+// Output buffers the Line to be written to the consumer, if it is of the Stream
+func (p *Pipe) Output(l Line) {
+	if p.Stream != "" && l.Stream != p.Stream {
+		return
+	}
+	p.once.Do(func() { go p.pump() })
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for !p.closed && len(p.queue) >= max(p.Size, 1) {
+		switch p.Policy {
+		case PipeBlock:
+			p.cond.Wait()
+			continue
+		case PipeDropNewest:
+			p.dropped.Add(1)
+			return
+		default:
+			p.queue = p.queue[1:]
+			p.dropped.Add(1)
+		}
+	}
+	if p.closed {
+		return
+	}
+	b := l.Bytes[:len(l.Bytes):len(l.Bytes)]
+	if !l.Partial {
+		b = append(b, '\n')
+	}
+	p.queue = append(p.queue, b)
+	p.cond.Broadcast()
+}
+
+// Dropped returns the number of Lines dropped because the buffer was full
+func (p *Pipe) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Buffered returns the number of Lines waiting to be written to the consumer
+func (p *Pipe) Buffered() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.queue)
+}
+
+// Close stops the Pipe, dropping any buffered Lines
+func (p *Pipe) Close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	p.queue = nil
+	p.cond.Broadcast()
+}
+
+// pump writes buffered Lines to the consumer, retrying each until it is taken, until the
+// Pipe is Closed
+func (p *Pipe) pump() {
+	for {
+		p.lock.Lock()
+		for !p.closed && len(p.queue) == 0 {
+			p.cond.Wait()
+		}
+		if p.closed {
+			p.lock.Unlock()
+			return
+		}
+		b := p.queue[0]
+		p.lock.Unlock()
+
+		if _, err := p.To.Write(b); err != nil {
+			// The consumer is down, or between runs
+			time.Sleep(p.RetryInterval)
+			continue
+		}
+
+		p.lock.Lock()
+		if len(p.queue) > 0 && &p.queue[0][0] == &b[0] {
+			// Not dropped while writing
+			p.queue = p.queue[1:]
+		}
+		p.cond.Broadcast()
+		p.lock.Unlock()
+	}
+}
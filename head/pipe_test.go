@@ -0,0 +1,114 @@
+package head
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_ToPipePolicy(t *testing.T) {
+	Convey("When pipe policies are parsed, the known ones are, and nonsense is an error", t, func() {
+		for _, s := range []string{"drop-oldest", "drop-newest", "block"} {
+			p, err := ToPipePolicy(s)
+			So(err, ShouldBeNil)
+			So(p, ShouldEqual, PipePolicy(s))
+		}
+		_, err := ToPipePolicy("spill")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func Test_HeadWriteNoStdin(t *testing.T) {
+	Convey("When a Head that has never run is written to, it is an error", t, func() {
+		r := New("cat", nil, make(chan error, 1))
+		_, err := r.Write([]byte("hi\n"))
+		So(err, ShouldEqual, ErrNoStdin)
+	})
+}
+
+func Test_Pipe(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Pipe's consumer has never run", t, func() {
+		consumer := New("cat", nil, errorChan)
+		defer consumer.Stop()
+
+		Convey("with drop-oldest, the newest Size Lines are buffered", func() {
+			p := NewPipe(consumer, StreamStdout, 2, PipeDropOldest)
+			defer p.Close()
+			for _, s := range []string{"1", "2", "3"} {
+				p.Output(Line{Stream: StreamStdout, Bytes: []byte(s)})
+			}
+			p.Output(Line{Stream: StreamStderr, Bytes: []byte("nope")})
+			So(p.Buffered(), ShouldEqual, 2)
+			So(p.Dropped(), ShouldEqual, 1)
+
+			Convey("and when the consumer runs, they are written to it", func() {
+				consumer.Run()
+				time.Sleep(300 * time.Millisecond)
+				So(p.Buffered(), ShouldEqual, 0)
+				So(lineStrings(consumer.Tail(-1, StreamStdout)), ShouldResemble, []string{"2", "3"})
+			})
+		})
+
+		Convey("with drop-newest, the oldest Size Lines are buffered", func() {
+			p := NewPipe(consumer, "", 2, PipeDropNewest)
+			defer p.Close()
+			for _, s := range []string{"1", "2", "3"} {
+				p.Output(Line{Stream: StreamStdout, Bytes: []byte(s)})
+			}
+			So(p.Buffered(), ShouldEqual, 2)
+			So(p.Dropped(), ShouldEqual, 1)
+
+			consumer.Run()
+			time.Sleep(300 * time.Millisecond)
+			So(lineStrings(consumer.Tail(-1, StreamStdout)), ShouldResemble, []string{"1", "2"})
+		})
+
+		Convey("with block, the producer waits for room", func() {
+			p := NewPipe(consumer, "", 1, PipeBlock)
+			defer p.Close()
+			p.Output(Line{Stream: StreamStdout, Bytes: []byte("1")})
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				p.Output(Line{Stream: StreamStdout, Bytes: []byte("2")})
+			}()
+			select {
+			case <-done:
+				So("not blocked", ShouldBeEmpty)
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			consumer.Run()
+			<-done
+			time.Sleep(300 * time.Millisecond)
+			So(p.Dropped(), ShouldEqual, 0)
+			So(lineStrings(consumer.Tail(-1, StreamStdout)), ShouldResemble, []string{"1", "2"})
+		})
+	})
+
+	Convey("When a producer Head pipes to an autorestarting consumer Head that restarts", t, func() {
+		consumer := BashDashC("read line; echo got $line; read line; echo got $line", errorChan)
+		consumer.Autorestart(true)
+		consumer.RestartDelay = 100 * time.Millisecond
+		defer consumer.Stop()
+
+		producer := BashDashC("for i in 1 2 3 4; do echo $i; echo noise >&2; sleep 0.05; done; sleep 30", errorChan)
+		p := NewPipe(consumer, StreamStdout, 10, PipeDropOldest)
+		defer p.Close()
+		producer.OutputSinks = []OutputSink{p}
+		defer producer.Stop()
+
+		consumer.Run()
+		producer.Run()
+
+		Convey("the Lines of the stream reach each run of the consumer", func() {
+			time.Sleep(800 * time.Millisecond)
+			So(consumer.Restarts(), ShouldBeGreaterThanOrEqualTo, 1)
+			So(lineStrings(consumer.Tail(-1, StreamStdout)), ShouldResemble, []string{"got 1", "got 2", "got 3", "got 4"})
+		})
+	})
+}
@@ -0,0 +1,247 @@
+package head
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrLivenessFailed is the cause of a process being killed for failing its LiveProbe
+var ErrLivenessFailed = errors.New("liveness probe failed")
+
+// Probe is a check made against a Head's running process. It passes if Probe returns nil.
+type Probe interface {
+	Probe(ctx context.Context) error
+}
+
+// TCPProbe passes if a TCP connection can be made to Port on localhost
+type TCPProbe struct {
+	Port int
+}
+
+// Probe implements Probe
+func (p TCPProbe) Probe(ctx context.Context) error {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", net.JoinHostPort("localhost", strconv.Itoa(p.Port)))
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+// String returns the Probe in a line
+func (p TCPProbe) String() string {
+	return fmt.Sprintf("tcp:%d", p.Port)
+}
+
+// FileProbe passes if Path exists
+type FileProbe struct {
+	Path string
+}
+
+// Probe implements Probe
+func (p FileProbe) Probe(ctx context.Context) error {
+	_, err := os.Stat(p.Path)
+	return err
+}
+
+// String returns the Probe in a line
+func (p FileProbe) String() string {
+	return fmt.Sprintf("file:%s", p.Path)
+}
+
+// HTTPProbe passes if a GET of Path from Port on localhost returns a 2xx or 3xx status
+type HTTPProbe struct {
+	Port int
+	Path string
+}
+
+// Probe implements Probe
+func (p HTTPProbe) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unhealthy status '%s'", resp.Status)
+	}
+	return nil
+}
+
+// String returns the Probe in a line
+func (p HTTPProbe) String() string {
+	return fmt.Sprintf("http:%s", p.url())
+}
+
+// url returns the URL to GET
+func (p HTTPProbe) url() string {
+	path := p.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort("localhost", strconv.Itoa(p.Port)), path)
+}
+
+// ExecProbe passes if Command, run with Args, exits 0
+type ExecProbe struct {
+	Command string
+	Args    []string
+}
+
+// Probe implements Probe
+func (p ExecProbe) Probe(ctx context.Context) error {
+	//#nosec G204 -- Yes. We have to trust the configs.
+	return exec.CommandContext(ctx, p.Command, p.Args...).Run()
+}
+
+// String returns the Probe in a line
+func (p ExecProbe) String() string {
+	return fmt.Sprintf("exec:%s", p.Command)
+}
+
+// readiness is closed, once, when a run is ready
+type readiness struct {
+	ch   chan struct{}
+	once sync.Once
+}
+
+// newReadiness returns a readiness that isn't ready yet
+func newReadiness() *readiness {
+	return &readiness{ch: make(chan struct{})}
+}
+
+// set marks the run ready
+func (rd *readiness) set() {
+	rd.once.Do(func() { close(rd.ch) })
+}
+
+// readyHook returns a line handler that marks the run ready if the line matches
+// ReadyPattern, or nil if there isn't one
+func (r *Head) readyHook(rd *readiness) func([]byte) {
+	if r.ReadyPattern == nil {
+		return nil
+	}
+	return func(line []byte) {
+		if r.ReadyPattern.Match(line) {
+			rd.set()
+		}
+	}
+}
+
+// waitReady runs the ReadyProbe every ReadyInterval until it passes, and moves the
+// Head to StateReady once the run is ready, unless ctx is done first.
+func (r *Head) waitReady(ctx context.Context, name string, pid int, rd *readiness) {
+	var probing sync.WaitGroup
+	defer probing.Wait()
+
+	if r.ReadyProbe != nil {
+		probing.Add(1)
+		go func() {
+			defer probing.Done()
+
+			interval := r.ReadyInterval
+			if interval <= 0 {
+				interval = time.Second
+			}
+			for {
+				pctx, cancel := context.WithTimeout(ctx, interval)
+				err := r.ReadyProbe.Probe(pctx)
+				cancel()
+				if err == nil {
+					rd.set()
+					return
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-rd.ch:
+					// Matched by ReadyPattern
+					return
+				case <-time.After(interval):
+				}
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-rd.ch:
+	}
+
+	if r.readyInstance(pid) {
+		r.DebugOut.Printf("%s/%s Ready", name, r.ID)
+		r.emit(Event{Type: EventReady, Name: name, PID: pid, ExitCode: -1})
+	}
+}
+
+// watchLive runs the LiveProbe every LiveInterval, after LiveDelay, until ctx is done.
+// After LiveFailures consecutive failures, kill is called with ErrLivenessFailed.
+// Probes are skipped while the Head is paused.
+func (r *Head) watchLive(ctx context.Context, name string, kill context.CancelCauseFunc) {
+	var (
+		interval = r.LiveInterval
+		timeout  = r.LiveTimeout
+		failures = r.LiveFailures
+		failed   int
+	)
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	if failures <= 0 {
+		failures = 3
+	}
+
+	wait := r.LiveDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = interval
+
+		if r.Paused() {
+			// A frozen process can't answer
+			failed = 0
+			continue
+		}
+
+		pctx, cancel := context.WithTimeout(ctx, timeout)
+		err := r.LiveProbe.Probe(pctx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		} else if err == nil {
+			failed = 0
+			continue
+		}
+
+		failed++
+		r.DebugOut.Printf("%s/%s LiveProbe failed (%d/%d): %s", name, r.ID, failed, failures, err)
+		if failed >= failures {
+			kill(fmt.Errorf("%w: %w", ErrLivenessFailed, err))
+			return
+		}
+	}
+}
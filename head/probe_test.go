@@ -0,0 +1,214 @@
+package head
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Probes(t *testing.T) {
+	Convey("When a TCPProbe is made against a listening port, it passes", t, func() {
+		l, err := net.Listen("tcp", "localhost:0")
+		So(err, ShouldBeNil)
+		port := l.Addr().(*net.TCPAddr).Port
+
+		So(TCPProbe{Port: port}.Probe(context.Background()), ShouldBeNil)
+
+		Convey("and when the port is closed, it fails", func() {
+			l.Close()
+			So(TCPProbe{Port: port}.Probe(context.Background()), ShouldNotBeNil)
+		})
+	})
+
+	Convey("When a FileProbe is made, it passes only if the file exists", t, func() {
+		path := filepath.Join(t.TempDir(), "ready")
+		So(FileProbe{Path: path}.Probe(context.Background()), ShouldNotBeNil)
+		So(os.WriteFile(path, nil, 0600), ShouldBeNil)
+		So(FileProbe{Path: path}.Probe(context.Background()), ShouldBeNil)
+	})
+
+	Convey("When an HTTPProbe is made, it passes only if the status is 2xx or 3xx", t, func() {
+		status := http.StatusOK
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/healthz" {
+				http.NotFound(w, req)
+				return
+			}
+			w.WriteHeader(status)
+		}))
+		defer s.Close()
+		port := s.Listener.Addr().(*net.TCPAddr).Port
+
+		So(HTTPProbe{Port: port, Path: "healthz"}.Probe(context.Background()), ShouldBeNil)
+		So(HTTPProbe{Port: port, Path: "/healthz"}.Probe(context.Background()), ShouldBeNil)
+		So(HTTPProbe{Port: port}.Probe(context.Background()), ShouldNotBeNil)
+		status = http.StatusServiceUnavailable
+		So(HTTPProbe{Port: port, Path: "/healthz"}.Probe(context.Background()), ShouldNotBeNil)
+	})
+
+	Convey("When an ExecProbe is made, it passes only if the command exits 0", t, func() {
+		So(ExecProbe{Command: "true"}.Probe(context.Background()), ShouldBeNil)
+		So(ExecProbe{Command: "false"}.Probe(context.Background()), ShouldNotBeNil)
+		So(ExecProbe{Command: "/does/not/exist"}.Probe(context.Background()), ShouldNotBeNil)
+	})
+}
+
+func Test_HeadReady(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head without readiness checks is running, it is never ready", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(200 * time.Millisecond)
+		So(r.Instance().State, ShouldEqual, StateRunning)
+	})
+
+	Convey("When a Head with a ReadyPattern is running", t, func() {
+		events := make(chan Event, 10)
+		r := BashDashC("sleep 0.3; echo listening on 8080 >&2; sleep 30", errorChan)
+		r.ReadyPattern = regexp.MustCompile(`^listening on \d+$`)
+		r.Subscribe(events)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+
+		Convey("it is starting until the line is output, and then it is ready", func() {
+			So(r.Instance().State, ShouldEqual, StateStarting)
+			So(r.Instance().PID, ShouldBeGreaterThan, 0)
+			So(r.Instance().Uptime(), ShouldBeGreaterThan, 0)
+			time.Sleep(400 * time.Millisecond)
+			So(r.Instance().State, ShouldEqual, StateReady)
+			So(eventTypes(events), ShouldContain, EventReady)
+		})
+
+		Convey("and when it is paused and resumed, it is still ready", func() {
+			time.Sleep(400 * time.Millisecond)
+			So(r.Pause(), ShouldBeNil)
+			So(r.Instance().State, ShouldEqual, StatePaused)
+			So(r.Resume(), ShouldBeNil)
+			So(r.Instance().State, ShouldEqual, StateReady)
+		})
+
+		Convey("and when it is paused and resumed before it is ready, it is still starting, and then ready", func() {
+			So(r.Pause(), ShouldBeNil)
+			So(r.Instance().State, ShouldEqual, StatePaused)
+			So(r.Resume(), ShouldBeNil)
+			So(r.Instance().State, ShouldEqual, StateStarting)
+			time.Sleep(400 * time.Millisecond)
+			So(r.Instance().State, ShouldEqual, StateReady)
+		})
+	})
+
+	Convey("When a Head with a ReadyProbe is running", t, func() {
+		path := filepath.Join(t.TempDir(), "ready")
+		r := New("sleep", []string{"30"}, errorChan)
+		r.ReadyProbe = FileProbe{Path: path}
+		r.ReadyInterval = 50 * time.Millisecond
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(200 * time.Millisecond)
+
+		Convey("it is starting until the probe passes, and then it is ready", func() {
+			So(r.Instance().State, ShouldEqual, StateStarting)
+			So(os.WriteFile(path, nil, 0600), ShouldBeNil)
+			time.Sleep(200 * time.Millisecond)
+			So(r.Instance().State, ShouldEqual, StateReady)
+		})
+	})
+
+	Convey("When an autorestarting Head with a ReadyProbe that passes is restarted", t, func() {
+		events := make(chan Event, 20)
+		r := BashDashC("sleep 0.3", errorChan)
+		r.ReadyProbe = ExecProbe{Command: "true"}
+		r.ReadyInterval = 50 * time.Millisecond
+		r.Autorestart(true)
+		r.RestartDelay = 100 * time.Millisecond
+		r.Subscribe(events)
+		defer r.Stop()
+
+		r.Run()
+
+		Convey("each run becomes ready", func() {
+			time.Sleep(600 * time.Millisecond)
+			So(r.Instance().State, ShouldEqual, StateReady)
+			So(eventTypes(events), ShouldResemble, []EventType{
+				EventStarting, EventStarted, EventReady, EventExited, EventRestarting,
+				EventStarting, EventStarted, EventReady,
+			})
+		})
+	})
+}
+
+func Test_HeadLive(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head with a LiveProbe that passes is running, it keeps running", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		r.LiveProbe = ExecProbe{Command: "true"}
+		r.LiveInterval = 50 * time.Millisecond
+		r.LiveFailures = 1
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(300 * time.Millisecond)
+		So(r.Instance().State, ShouldEqual, StateRunning)
+	})
+
+	Convey("When a Head with a LiveProbe that fails is running", t, func() {
+		events := make(chan Event, 10)
+		r := New("sleep", []string{"30"}, errorChan)
+		r.LiveProbe = ExecProbe{Command: "false"}
+		r.LiveInterval = 50 * time.Millisecond
+		r.LiveFailures = 3
+		r.LiveDelay = 300 * time.Millisecond
+		r.StopTimeout = 0
+		r.Subscribe(events)
+		defer r.Stop()
+
+		r.Run()
+
+		Convey("it is left alone for the LiveDelay, and then killed after LiveFailures", func() {
+			time.Sleep(250 * time.Millisecond)
+			So(r.Instance().State, ShouldEqual, StateRunning)
+
+			r.Wait()
+			h := r.History()
+			So(h, ShouldHaveLength, 1)
+			So(h[0].Reason, ShouldEqual, ExitLiveness)
+			So(h[0].End.Sub(h[0].Start), ShouldBeGreaterThanOrEqualTo, 400*time.Millisecond)
+			So(eventTypes(events), ShouldContain, EventLivenessFailed)
+		})
+	})
+
+	Convey("When an on-failure autorestarting Head with a LiveProbe that fails exits 0 when killed", t, func() {
+		r := BashDashC("trap 'exit 0' TERM; while true; do sleep 0.05; done", errorChan)
+		r.LiveProbe = ExecProbe{Command: "false"}
+		r.LiveInterval = 50 * time.Millisecond
+		r.LiveFailures = 1
+		r.LiveDelay = 100 * time.Millisecond // for the trap to be set
+		r.RestartOn = RestartOnFailure
+		r.Autorestart(true)
+		defer r.Stop()
+
+		r.Run()
+
+		Convey("it is still restarted", func() {
+			time.Sleep(400 * time.Millisecond)
+			So(r.Restarts(), ShouldBeGreaterThanOrEqualTo, 1)
+			So(r.History()[0].Reason, ShouldEqual, ExitLiveness)
+			So(r.History()[0].ExitCode, ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,117 @@
+package head
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// groupPollInterval is how often a stopped process group is checked for survivors, once its
+// leader has exited
+var groupPollInterval = 100 * time.Millisecond
+
+// groupSweep kills whatever is left of a stopped process group after StopTimeout
+type groupSweep struct {
+	r        *Head
+	name     string
+	pgid     int
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// stopGroup sends StopSignal to the process group led by pgid, and returns the groupSweep that
+// kills whatever is left of the group after StopTimeout. If there is no StopTimeout, or
+// StopSignal cannot be sent to a group, the group is killed immediately, and there is no sweep.
+func (r *Head) stopGroup(name string, pgid int) (*groupSweep, error) {
+	sig, ok := r.StopSignal.(syscall.Signal)
+	if r.StopTimeout <= 0 || !ok {
+		r.killGroup(name, pgid)
+		return nil, nil
+	}
+
+	s := &groupSweep{r: r, name: name, pgid: pgid, deadline: time.Now().Add(r.StopTimeout)}
+	s.timer = time.AfterFunc(r.StopTimeout, func() { r.killGroup(name, pgid) })
+	if err := syscall.Kill(-pgid, sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return s, os.ErrProcessDone
+		}
+		return s, err
+	}
+	return s, nil
+}
+
+// finish is called once the group leader has been waited on. It stops the timer, as once the
+// whole group is gone its pgid may be reused, and instead kills whatever is left of the group
+// at the deadline, unless the group is gone by then.
+func (s *groupSweep) finish() {
+	if !s.timer.Stop() {
+		// Already swept
+		return
+	}
+	for time.Now().Before(s.deadline) {
+		if !groupAlive(s.pgid) {
+			return
+		}
+		time.Sleep(groupPollInterval)
+	}
+	if groupAlive(s.pgid) {
+		s.r.killGroup(s.name, s.pgid)
+	}
+}
+
+// groupAlive returns true if there are any processes in the process group pgid
+func groupAlive(pgid int) bool {
+	err := syscall.Kill(-pgid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// killGroup SIGKILLs the process group, reporting any live members to DebugOut.
+func (r *Head) killGroup(name string, pgid int) {
+	if survivors := groupMembers(pgid); len(survivors) > 0 {
+		r.DebugOut.Printf("%s/%s: force-killing %d survivor(s) of process group %d: %s\n", name, r.ID, len(survivors), pgid, strings.Join(survivors, ", "))
+	}
+	if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		r.errorHandler(fmt.Errorf("%s/%s: 'killgroup' %w", name, r.ID, err))
+	}
+}
+
+// groupMembers returns "pid (command)" for every live process in the process group pgid,
+// by scanning /proc, if it can. Zombies are ignored, as they are already dead.
+func groupMembers(pgid int) []string {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return nil
+	}
+
+	var members []string
+	for _, stat := range stats {
+		b, err := os.ReadFile(stat)
+		if err != nil {
+			// Probably exited while we were looking
+			continue
+		}
+
+		// pid (comm) state ppid pgrp ... and comm may contain anything, including parens
+		lp := bytes.IndexByte(b, '(')
+		rp := bytes.LastIndexByte(b, ')')
+		if lp < 0 || rp < lp {
+			continue
+		}
+		fields := strings.Fields(string(b[rp+1:]))
+		if len(fields) < 3 || fields[0] == "Z" {
+			continue
+		}
+		if pgrp, err := strconv.Atoi(fields[2]); err != nil || pgrp != pgid {
+			continue
+		}
+
+		members = append(members, fmt.Sprintf("%s %s", strings.TrimSpace(string(b[:lp])), b[lp:rp+1]))
+	}
+	return members
+}
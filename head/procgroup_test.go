@@ -0,0 +1,151 @@
+package head
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_GroupMembers(t *testing.T) {
+	Convey("When the members of our own process group are listed, we are in there", t, func() {
+		pgid, err := syscall.Getpgid(os.Getpid())
+		So(err, ShouldBeNil)
+
+		members := groupMembers(pgid)
+		So(members, ShouldContain, fmt.Sprintf("%d (%s)", os.Getpid(), procName(os.Getpid())))
+	})
+
+	Convey("When the members of a non-existent process group are listed, there are none", t, func() {
+		So(groupMembers(-10), ShouldBeEmpty)
+	})
+}
+
+func Test_HeadProcessGroupStop(t *testing.T) {
+	errorChan := make(chan error, 1)
+	var buf Sbuffer
+
+	Convey("When a Head Initializes in its own process group, and forks a grandchild", t, func() {
+		r := BashDashC("sleep 30 & echo $!; wait", errorChan)
+		defer r.Stop()
+		r.ProcessGroup = true
+		r.StdOut = log.New(&buf, "", 0)
+
+		Convey("and a Stop is issued, the grandchild is stopped too", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			time.Sleep(500 * time.Millisecond)
+			r.Stop()
+			r.Wait()
+
+			pid, err := strconv.Atoi(strings.TrimSpace(buf.String()))
+			So(err, ShouldBeNil)
+			So(procDies(pid), ShouldBeTrue)
+		})
+	})
+}
+
+func Test_HeadProcessGroupSurvivors(t *testing.T) {
+	errorChan := make(chan error, 1)
+	var (
+		buf   Sbuffer
+		debug Sbuffer
+	)
+
+	Convey("When a Head Initializes in its own process group, and it and its grandchild ignore SIGTERM", t, func() {
+		r := BashDashC("trap '' TERM; sleep 30 & echo $!; wait", errorChan)
+		defer r.Stop()
+		r.ProcessGroup = true
+		r.StopTimeout = 500 * time.Millisecond
+		r.StdOut = log.New(&buf, "", 0)
+		r.DebugOut = log.New(&debug, "", 0)
+
+		Convey("and a Stop is issued, the survivors are force-killed after StopTimeout, and reported", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			time.Sleep(500 * time.Millisecond)
+			start := time.Now()
+			r.Stop()
+			r.Wait()
+			So(time.Since(start), ShouldBeLessThan, 2*time.Second)
+
+			pid, err := strconv.Atoi(strings.TrimSpace(buf.String()))
+			So(err, ShouldBeNil)
+			So(procDies(pid), ShouldBeTrue)
+			So(debug.String(), ShouldContainSubstring, fmt.Sprintf("%d (sleep)", pid))
+		})
+	})
+}
+
+func Test_HeadProcessGroupOutlivesLeader(t *testing.T) {
+	errorChan := make(chan error, 1)
+	var (
+		buf   Sbuffer
+		debug Sbuffer
+	)
+
+	Convey("When a Head Initializes in its own process group, and only its grandchild ignores SIGTERM", t, func() {
+		r := BashDashC("(trap '' TERM; sleep 30) & echo $!; wait", errorChan)
+		defer r.Stop()
+		r.ProcessGroup = true
+		r.StopTimeout = 500 * time.Millisecond
+		r.StdOut = log.New(&buf, "", 0)
+		r.DebugOut = log.New(&debug, "", 0)
+
+		Convey("and a Stop is issued, the group is still force-killed after StopTimeout once its leader has exited", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			time.Sleep(500 * time.Millisecond)
+			start := time.Now()
+			r.Stop()
+			r.Wait()
+			So(time.Since(start), ShouldBeBetween, r.StopTimeout, 2*time.Second)
+
+			pid, err := strconv.Atoi(strings.TrimSpace(buf.String()))
+			So(err, ShouldBeNil)
+			So(procDies(pid), ShouldBeTrue)
+			So(debug.String(), ShouldContainSubstring, "force-killing")
+		})
+	})
+}
+
+func Test_GroupAlive(t *testing.T) {
+	Convey("When our own process group is checked, it is alive", t, func() {
+		pgid, err := syscall.Getpgid(os.Getpid())
+		So(err, ShouldBeNil)
+		So(groupAlive(pgid), ShouldBeTrue)
+	})
+}
+
+// procName returns the comm of the pid
+func procName(pid int) string {
+	b, _ := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	return strings.TrimSpace(string(b))
+}
+
+// procDies returns true if the pid is no longer alive within a second
+func procDies(pid int) bool {
+	for range 10 {
+		if !procAlive(pid) {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+// procAlive returns true if the pid exists and is not a zombie
+func procAlive(pid int) bool {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(b[strings.LastIndexByte(string(b), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
@@ -0,0 +1,106 @@
+package head
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPTY returns the master and slave of a new pseudo-terminal of the window size. The slave
+// has echo off and doesn't translate newlines, so what is written to the master isn't echoed
+// back, and lines read from the master end in a newline alone.
+func openPTY(rows, cols uint16) (master, tty *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			master.Close()
+		}
+	}()
+
+	// Not master.Fd(), which would make it blocking, so closing it couldn't end a Read
+	rc, err := master.SyscallConn()
+	if err != nil {
+		return nil, nil, err
+	}
+	var n int
+	if cerr := rc.Control(func(fd uintptr) {
+		if err = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0); err != nil {
+			err = fmt.Errorf("unlockpt: %w", err)
+			return
+		}
+		if n, err = unix.IoctlGetInt(int(fd), unix.TIOCGPTN); err != nil {
+			err = fmt.Errorf("ptsname: %w", err)
+		}
+	}); cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	tty, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tty.Close()
+		}
+	}()
+
+	tfd := int(tty.Fd())
+	if err = unix.IoctlSetWinsize(tfd, unix.TIOCSWINSZ, &unix.Winsize{Row: rows, Col: cols}); err != nil {
+		return nil, nil, fmt.Errorf("setting window size: %w", err)
+	}
+	t, err := unix.IoctlGetTermios(tfd, unix.TCGETS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting termios: %w", err)
+	}
+	t.Lflag &^= unix.ECHO | unix.ECHONL
+	t.Oflag &^= unix.ONLCR
+	if err = unix.IoctlSetTermios(tfd, unix.TCSETS, t); err != nil {
+		return nil, nil, fmt.Errorf("setting termios: %w", err)
+	}
+	return master, tty, nil
+}
+
+// ptyPipes sets the cmd to run on a new pseudo-terminal of the window size, as its controlling
+// terminal, and returns the master to read its output from and write its input to, and the
+// slave, which must be closed once the cmd is started. The cmd must have SysProcAttr.
+func ptyPipes(cmd *exec.Cmd, rows, cols uint16) (stdout io.ReadCloser, stdin io.WriteCloser, tty io.Closer, err error) {
+	master, slave, err := openPTY(rows, cols)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("'pty' %w", err)
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+
+	// A new session, with the PTY as its controlling terminal (stdin), also leads a new
+	// process group.
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+	cmd.SysProcAttr.Setpgid = false
+	return ptyReader{master}, master, slave, nil
+}
+
+// ptyReader is the master of a pseudo-terminal, read until the slave is closed by all
+// processes, which is EOF rather than the EIO Linux reports
+type ptyReader struct {
+	*os.File
+}
+
+// Read reads from the master, returning io.EOF once the slave is closed
+func (p ptyReader) Read(b []byte) (int, error) {
+	n, err := p.File.Read(b)
+	if errors.Is(err, syscall.EIO) {
+		err = io.EOF
+	}
+	return n, err
+}
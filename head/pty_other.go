@@ -0,0 +1,14 @@
+//go:build !linux
+
+package head
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+)
+
+// ptyPipes is unsupported off of Linux
+func ptyPipes(cmd *exec.Cmd, rows, cols uint16) (stdout io.ReadCloser, stdin io.WriteCloser, tty io.Closer, err error) {
+	return nil, nil, nil, errors.New("'pty' PTY is only supported on Linux")
+}
@@ -0,0 +1,98 @@
+package head
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_HeadPTY(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head with a PTY runs", t, func() {
+		r := BashDashC("tty; stty size; test -t 0 && test -t 1 && test -t 2 && echo all ttys; echo to stderr >&2", errorChan)
+		r.PTY = true
+		r.PTYRows = 40
+		r.PTYCols = 120
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+
+		Convey("its stdin, stdout and stderr are the terminal, of the window size, and its output is read", func() {
+			lines := lineStrings(r.Tail(-1, StreamStdout))
+			So(lines, ShouldHaveLength, 4)
+			So(lines[0], ShouldStartWith, "/dev/pts/")
+			So(lines[1:], ShouldResemble, []string{"40 120", "all ttys", "to stderr"})
+			So(r.History()[0].ExitCode, ShouldEqual, 0)
+		})
+	})
+
+	Convey("When a Head with a PTY is written to", t, func() {
+		r := BashDashC("read line; echo got $line", errorChan)
+		r.PTY = true
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		_, err := r.Write([]byte("hello\n"))
+		So(err, ShouldBeNil)
+		r.Wait()
+
+		Convey("it reads what was written, without it being echoed", func() {
+			So(lineStrings(r.Tail(-1, "")), ShouldResemble, []string{"got hello"})
+		})
+	})
+
+	Convey("When a Head with a PTY that runs in its own process group is stopped", t, func() {
+		r := BashDashC("sleep 30 & wait", errorChan)
+		r.PTY = true
+		r.ProcessGroup = true
+		r.StopTimeout = 0
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+
+		Convey("it and its children are stopped", func() {
+			r.Stop()
+			r.Wait()
+			So(r.History()[0].Reason, ShouldEqual, ExitStopped)
+		})
+	})
+
+	Convey("When an autorestarting Head with a PTY restarts, each run gets a terminal", t, func() {
+		r := BashDashC("test -t 1 && echo tty", errorChan)
+		r.PTY = true
+		r.Autorestart(true)
+		r.RestartDelay = 50 * time.Millisecond
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(300 * time.Millisecond)
+		So(r.Restarts(), ShouldBeGreaterThanOrEqualTo, 1)
+		lines := lineStrings(r.Tail(-1, StreamStdout))
+		So(len(lines), ShouldBeGreaterThanOrEqualTo, 2)
+		So(lines[:2], ShouldResemble, []string{"tty", "tty"})
+	})
+}
+
+func Test_HeadPTYHeldOpen(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head with a PTY exits, leaving a backgrounded child holding the terminal open", t, func() {
+		r := BashDashC("(trap '' HUP; sleep 5) & echo hi; exit 3", errorChan)
+		r.PTY = true
+		defer r.Stop()
+
+		start := time.Now()
+		r.Run()
+		r.Wait()
+
+		Convey("its exit is seen, and its output read, without waiting for the child", func() {
+			So(time.Since(start), ShouldBeLessThan, outputDrainTimeout+time.Second)
+			So(r.History(), ShouldHaveLength, 1)
+			So(r.History()[0].ExitCode, ShouldEqual, 3)
+			So(lineStrings(r.Tail(-1, StreamStdout)), ShouldResemble, []string{"hi"})
+		})
+	})
+}
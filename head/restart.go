@@ -0,0 +1,169 @@
+package head
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RestartPolicy decides how long a Head waits before restarting a process. The attempt is
+// the number of consecutive restarts before this one, starting at 0, and is reset when a
+// process stays up for at least the Head's StableUptime.
+type RestartPolicy interface {
+	Delay(attempt int) time.Duration
+}
+
+// FixedRestart is a RestartPolicy that always waits the same duration
+type FixedRestart struct {
+	// Wait is the duration to wait between restarts
+	Wait time.Duration
+}
+
+// Delay returns the fixed duration, regardless of attempt
+func (f *FixedRestart) Delay(attempt int) time.Duration {
+	return f.Wait
+}
+
+// ExponentialRestart is a RestartPolicy that waits Base, then Base*Factor, then Base*Factor^2...
+// up to Max
+type ExponentialRestart struct {
+	// Base is the duration to wait before the first restart. Values of 0 or less are treated as 1s,
+	// as there would be no backoff at all
+	Base time.Duration
+	// Factor is the multiplier applied for each consecutive restart. Values below 1 are treated as 2
+	Factor float64
+	// Max is the longest duration to wait. If 0, there is no maximum
+	Max time.Duration
+}
+
+// Delay returns the exponentially-increasing duration for the attempt
+func (e *ExponentialRestart) Delay(attempt int) time.Duration {
+	base := e.Base
+	if base <= 0 {
+		base = time.Second
+	}
+	factor := e.Factor
+	if factor < 1 {
+		factor = 2
+	}
+
+	d := float64(base) * math.Pow(factor, float64(attempt))
+	if e.Max > 0 && d > float64(e.Max) {
+		return e.Max
+	} else if d >= math.MaxInt64 {
+		// Overflow. float64(math.MaxInt64) is 2^63, which doesn't fit either
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+// JitterRestart is an ExponentialRestart that randomly shortens each delay by up to Jitter,
+// so that many processes crashing together don't all restart together.
+type JitterRestart struct {
+	ExponentialRestart
+	// Jitter is the largest fraction, between 0 and 1, of each delay that may be randomly removed.
+	// Values outside of that range are treated as 1
+	Jitter float64
+}
+
+// Delay returns the exponentially-increasing duration for the attempt, less some jitter
+func (j *JitterRestart) Delay(attempt int) time.Duration {
+	jitter := j.Jitter
+	if jitter <= 0 || jitter > 1 {
+		jitter = 1
+	}
+
+	d := j.ExponentialRestart.Delay(attempt)
+	//#nosec G404 -- Jitter needn't be cryptographically secure.
+	return d - time.Duration(float64(d)*jitter*rand.Float64())
+}
+
+// RestartCondition is a condition under which a completed process is restarted
+type RestartCondition string
+
+// RestartConditions
+const (
+	// RestartAlways restarts regardless of how the process exited
+	RestartAlways = RestartCondition("always")
+	// RestartOnFailure restarts if the process exited with an unclean exit code, was killed by
+	// an unclean signal, timed out, or failed to start
+	RestartOnFailure = RestartCondition("on-failure")
+	// RestartOnAbnormal restarts if the process was killed by an unclean signal, or timed out
+	RestartOnAbnormal = RestartCondition("on-abnormal")
+	// RestartNever never restarts
+	RestartNever = RestartCondition("never")
+)
+
+// cleanSignals are terminating signals that are considered a clean exit
+var cleanSignals = []syscall.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGPIPE}
+
+// ToRestartCondition returns the RestartCondition of the string, or an error.
+func ToRestartCondition(s string) (RestartCondition, error) {
+	switch rc := RestartCondition(strings.ToLower(s)); rc {
+	case RestartAlways, RestartOnFailure, RestartOnAbnormal, RestartNever:
+		return rc, nil
+	case "":
+		return RestartAlways, nil
+	default:
+		return "", fmt.Errorf("unknown restart condition '%s'", s)
+	}
+}
+
+// restartOn returns RestartOn, or the default if unset
+func (r *Head) restartOn() RestartCondition {
+	if r.RestartOn == "" {
+		return RestartAlways
+	}
+	return r.RestartOn
+}
+
+// shouldRestart evaluates the RestartOn condition against how a process exited. A nil state
+// is a process that failed to start. killed is true if the Head killed the process for
+// misbehaving, i.e. a Timeout, failed LiveProbe or Trigger.
+func (r *Head) shouldRestart(state *os.ProcessState, killed bool) bool {
+	var (
+		exitCode = -1
+		signal   syscall.Signal
+		signaled bool
+	)
+	if state != nil {
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			signal = ws.Signal()
+			signaled = true
+		} else {
+			exitCode = state.ExitCode()
+		}
+	}
+
+	// Prevention trumps everything
+	if signaled {
+		for _, s := range r.RestartPreventSignals {
+			if s == signal {
+				return false
+			}
+		}
+	} else if state != nil && slices.Contains(r.RestartPreventExitCodes, exitCode) {
+		return false
+	}
+
+	var (
+		abnormal = killed || (signaled && !slices.Contains(cleanSignals, signal))
+		failure  = abnormal || state == nil || (!signaled && exitCode != 0 && !slices.Contains(r.SuccessExitCodes, exitCode))
+	)
+
+	switch r.restartOn() {
+	case RestartNever:
+		return false
+	case RestartOnFailure:
+		return failure
+	case RestartOnAbnormal:
+		return abnormal
+	default:
+		return true
+	}
+}
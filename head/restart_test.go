@@ -0,0 +1,225 @@
+package head
+
+import (
+	"math"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_FixedRestart(t *testing.T) {
+	Convey("When a FixedRestart is used, every attempt waits the same", t, func() {
+		p := &FixedRestart{Wait: time.Second}
+		for a := range 10 {
+			So(p.Delay(a), ShouldEqual, time.Second)
+		}
+	})
+}
+
+func Test_ExponentialRestart(t *testing.T) {
+	Convey("When an ExponentialRestart is used", t, func() {
+		p := &ExponentialRestart{Base: 100 * time.Millisecond, Factor: 2, Max: time.Second}
+
+		Convey("each attempt waits longer, up to the Max", func() {
+			So(p.Delay(0), ShouldEqual, 100*time.Millisecond)
+			So(p.Delay(1), ShouldEqual, 200*time.Millisecond)
+			So(p.Delay(2), ShouldEqual, 400*time.Millisecond)
+			So(p.Delay(3), ShouldEqual, 800*time.Millisecond)
+			So(p.Delay(4), ShouldEqual, time.Second)
+			So(p.Delay(1000), ShouldEqual, time.Second)
+		})
+
+		Convey("with no Factor or Max, it doubles without overflowing", func() {
+			p.Factor = 0
+			p.Max = 0
+			So(p.Delay(1), ShouldEqual, 200*time.Millisecond)
+			So(p.Delay(1000), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("with no Base, it backs off from 1s, and doesn't go NaN", func() {
+			p.Base = 0
+			p.Max = 0
+			So(p.Delay(0), ShouldEqual, time.Second)
+			So(p.Delay(1), ShouldEqual, 2*time.Second)
+			So(p.Delay(100000), ShouldEqual, time.Duration(math.MaxInt64))
+		})
+
+		Convey("when a delay is exactly 2^63ns, it doesn't overflow either", func() {
+			p.Base = time.Nanosecond
+			p.Max = 0
+			So(p.Delay(63), ShouldEqual, time.Duration(math.MaxInt64))
+		})
+	})
+}
+
+func Test_JitterRestart(t *testing.T) {
+	Convey("When a JitterRestart is used, each attempt waits no longer than the exponential delay, and no shorter than the jitter allows", t, func() {
+		p := &JitterRestart{
+			ExponentialRestart: ExponentialRestart{Base: 100 * time.Millisecond, Factor: 2, Max: time.Second},
+			Jitter:             0.5,
+		}
+		for a := range 10 {
+			for range 100 {
+				d := p.Delay(a)
+				So(d, ShouldBeLessThanOrEqualTo, p.ExponentialRestart.Delay(a))
+				So(d, ShouldBeGreaterThanOrEqualTo, p.ExponentialRestart.Delay(a)/2)
+			}
+		}
+	})
+}
+
+func Test_HeadRestartPolicy(t *testing.T) {
+	errorChan := make(chan error, 1)
+	Convey("When a Head that autorestarts quickly-exiting processes uses an ExponentialRestart", t, func() {
+		r := New("false", nil, errorChan)
+		defer r.Stop()
+		r.Autorestart(true)
+		r.RestartPolicy = &ExponentialRestart{Base: 100 * time.Millisecond, Factor: 2}
+
+		Convey("it backs off, restarting only a few times in a second", func() {
+			time.AfterFunc(time.Second, func() { r.Stop() })
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+			So(r.Restarts(), ShouldBeBetweenOrEqual, 2, 4)
+		})
+	})
+
+	Convey("When a Head that autorestarts processes that run longer than StableUptime uses an ExponentialRestart", t, func() {
+		r := New("sleep", []string{"0.2"}, errorChan)
+		defer r.Stop()
+		r.Autorestart(true)
+		r.StableUptime = 100 * time.Millisecond
+		r.RestartPolicy = &ExponentialRestart{Base: 100 * time.Millisecond, Factor: 10}
+
+		Convey("it never backs off", func() {
+			time.AfterFunc(1500*time.Millisecond, func() { r.Stop() })
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+			So(r.Restarts(), ShouldBeGreaterThanOrEqualTo, 4)
+		})
+	})
+}
+
+func Test_ToRestartCondition(t *testing.T) {
+	Convey("When strings are converted to RestartConditions", t, func() {
+		for s, rc := range map[string]RestartCondition{
+			"always":      RestartAlways,
+			"":            RestartAlways,
+			"On-Failure":  RestartOnFailure,
+			"on-abnormal": RestartOnAbnormal,
+			"never":       RestartNever,
+		} {
+			c, err := ToRestartCondition(s)
+			So(err, ShouldBeNil)
+			So(c, ShouldEqual, rc)
+		}
+
+		_, err := ToRestartCondition("sometimes")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func Test_ShouldRestart(t *testing.T) {
+	// exited runs the bash script and returns its ProcessState
+	exited := func(script string) *os.ProcessState {
+		cmd := exec.Command("bash", "-c", script)
+		cmd.Run()
+		return cmd.ProcessState
+	}
+
+	var (
+		clean     = exited("exit 0")
+		three     = exited("exit 3")
+		termed    = exited("kill -TERM $$")
+		killed    = exited("kill -KILL $$")
+		errorChan = make(chan error, 1)
+	)
+
+	Convey("When a Head evaluates whether to restart", t, func() {
+		r := New("true", nil, errorChan)
+		defer r.Stop()
+
+		Convey("RestartAlways (the default) always does", func() {
+			So(r.shouldRestart(clean, false), ShouldBeTrue)
+			So(r.shouldRestart(three, false), ShouldBeTrue)
+			So(r.shouldRestart(killed, false), ShouldBeTrue)
+			So(r.shouldRestart(nil, false), ShouldBeTrue)
+		})
+
+		Convey("RestartNever never does", func() {
+			r.RestartOn = RestartNever
+			So(r.shouldRestart(clean, false), ShouldBeFalse)
+			So(r.shouldRestart(killed, true), ShouldBeFalse)
+		})
+
+		Convey("RestartOnFailure does for unclean exits, kills, timeouts and start failures", func() {
+			r.RestartOn = RestartOnFailure
+			So(r.shouldRestart(clean, false), ShouldBeFalse)
+			So(r.shouldRestart(termed, false), ShouldBeFalse)
+			So(r.shouldRestart(three, false), ShouldBeTrue)
+			So(r.shouldRestart(killed, false), ShouldBeTrue)
+			So(r.shouldRestart(termed, true), ShouldBeTrue)
+			So(r.shouldRestart(nil, false), ShouldBeTrue)
+
+			Convey("unless the exit code is a SuccessExitCode", func() {
+				r.SuccessExitCodes = []int{3}
+				So(r.shouldRestart(three, false), ShouldBeFalse)
+			})
+		})
+
+		Convey("RestartOnAbnormal does for kills and timeouts only", func() {
+			r.RestartOn = RestartOnAbnormal
+			So(r.shouldRestart(clean, false), ShouldBeFalse)
+			So(r.shouldRestart(three, false), ShouldBeFalse)
+			So(r.shouldRestart(termed, false), ShouldBeFalse)
+			So(r.shouldRestart(killed, false), ShouldBeTrue)
+			So(r.shouldRestart(clean, true), ShouldBeTrue)
+		})
+
+		Convey("Prevented exit codes and signals never do", func() {
+			r.RestartPreventExitCodes = []int{3}
+			r.RestartPreventSignals = []os.Signal{syscall.SIGKILL}
+			So(r.shouldRestart(clean, false), ShouldBeTrue)
+			So(r.shouldRestart(three, false), ShouldBeFalse)
+			So(r.shouldRestart(killed, false), ShouldBeFalse)
+		})
+	})
+}
+
+func Test_HeadRestartOn(t *testing.T) {
+	errorChan := make(chan error, 10)
+	Convey("When a Head that autorestarts on-failure runs a command that succeeds", t, func() {
+		r := New("true", nil, errorChan)
+		defer r.Stop()
+		r.Autorestart(true)
+		r.RestartOn = RestartOnFailure
+
+		Convey("it does not restart", func() {
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+			So(r.Restarts(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("When a Head that autorestarts on-failure runs a command that fails", t, func() {
+		r := New("false", nil, errorChan)
+		defer r.Stop()
+		r.Autorestart(true)
+		r.RestartOn = RestartOnFailure
+
+		Convey("it restarts", func() {
+			time.AfterFunc(500*time.Millisecond, func() { r.Stop() })
+			name := r.Run()
+			So(name, ShouldNotBeZeroValue)
+			r.Wait()
+			So(r.Restarts(), ShouldBeGreaterThan, 0)
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package head
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Signal sends the signal to the running process, or its process group if ProcessGroup.
+// SIGSTOP and SIGCONT are refused, as Pause and Resume need to track those.
+func (r *Head) Signal(sig os.Signal) error {
+	s, err := allowedSignal(sig)
+	if err != nil {
+		return err
+	}
+
+	i := r.Instance()
+	if !i.running() && i.State != StatePaused {
+		return fmt.Errorf("no running process to signal")
+	}
+	return r.sendSignal(i.PID, s)
+}
+
+// allowedSignal returns the sig as a syscall.Signal, or an error if it may not be sent
+func allowedSignal(sig os.Signal) (syscall.Signal, error) {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return 0, fmt.Errorf("unsupported signal '%s'", sig)
+	}
+	switch s {
+	case syscall.SIGSTOP, syscall.SIGCONT:
+		return 0, fmt.Errorf("signal '%s' not allowed, use Pause or Resume", s)
+	}
+	return s, nil
+}
+
+// sendSignal sends the signal to the pid, or its process group if ProcessGroup
+func (r *Head) sendSignal(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return fmt.Errorf("no process to signal")
+	}
+	if r.ProcessGroup {
+		pid = -pid
+	}
+	return syscall.Kill(pid, sig)
+}
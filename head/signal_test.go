@@ -0,0 +1,52 @@
+package head
+
+import (
+	"log"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_HeadSignal(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head has no running process, it can't be signalled", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		defer r.Stop()
+
+		So(r.Signal(syscall.SIGHUP), ShouldNotBeNil)
+	})
+
+	Convey("When a Head is running a process that traps SIGHUP", t, func() {
+		var out Sbuffer
+		r := BashDashC("trap 'echo hupped' HUP; while true; do sleep 0.05; done", errorChan)
+		r.StdOut = log.New(&out, "", 0)
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(200 * time.Millisecond)
+
+		Convey("and it is sent SIGHUP, the process handles it", func() {
+			So(r.Signal(syscall.SIGHUP), ShouldBeNil)
+			time.Sleep(200 * time.Millisecond)
+			So(out.String(), ShouldContainSubstring, "hupped")
+			So(r.Instance().State, ShouldEqual, StateRunning)
+		})
+
+		Convey("and it is sent SIGSTOP or SIGCONT, it is refused", func() {
+			So(r.Signal(syscall.SIGSTOP), ShouldNotBeNil)
+			So(r.Signal(syscall.SIGCONT), ShouldNotBeNil)
+		})
+
+		Convey("and it is sent os.Kill, the process dies", func() {
+			So(r.Signal(os.Kill), ShouldBeNil)
+			r.Wait()
+			h := r.History()
+			So(h, ShouldHaveLength, 1)
+			So(h[0].Signal, ShouldEqual, syscall.SIGKILL)
+		})
+	})
+}
@@ -0,0 +1,32 @@
+package head
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// permanentStartErrors are errors starting a process that will happen again if the start is retried
+var permanentStartErrors = []error{
+	exec.ErrNotFound,
+	exec.ErrDot,
+	syscall.ENOENT,
+	syscall.ENOTDIR,
+	syscall.EISDIR,
+	syscall.EACCES,
+	syscall.ENOEXEC,
+	syscall.EPERM,
+	syscall.EINVAL,
+}
+
+// IsPermanentStartError returns true if the error starting a process will happen again if the
+// start is retried, such as a missing or non-executable command, or a UID the process can't be
+// run as. Other start errors, such as running out of file descriptors, are transient.
+func IsPermanentStartError(err error) bool {
+	for _, perm := range permanentStartErrors {
+		if errors.Is(err, perm) {
+			return true
+		}
+	}
+	return false
+}
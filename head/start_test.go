@@ -0,0 +1,73 @@
+package head
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_IsPermanentStartError(t *testing.T) {
+	Convey("When start errors are classified, missing and non-executable commands are permanent, and others are transient", t, func() {
+		So(IsPermanentStartError(exec.ErrNotFound), ShouldBeTrue)
+		So(IsPermanentStartError(&os.PathError{Op: "fork/exec", Path: "/nope", Err: syscall.ENOENT}), ShouldBeTrue)
+		So(IsPermanentStartError(fmt.Errorf("wrapped: %w", syscall.EACCES)), ShouldBeTrue)
+		So(IsPermanentStartError(syscall.EPERM), ShouldBeTrue)
+		So(IsPermanentStartError(syscall.EAGAIN), ShouldBeFalse)
+		So(IsPermanentStartError(syscall.EMFILE), ShouldBeFalse)
+		So(IsPermanentStartError(nil), ShouldBeFalse)
+	})
+}
+
+func Test_HeadStartFailed(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When an autorestarting Head of a command that doesn't exist is run", t, func() {
+		events := make(chan Event, 10)
+		r := New("/does/not/exist", []string{}, errorChan)
+		r.Autorestart(true)
+		r.RestartDelay = 50 * time.Millisecond
+		r.Subscribe(events)
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+
+		Convey("it is Failed, and not restarted", func() {
+			So(r.State(), ShouldEqual, StateFailed)
+			So(r.Restarts(), ShouldEqual, 0)
+			So(r.History(), ShouldHaveLength, 1)
+			So(r.History()[0].Reason, ShouldEqual, ExitStartFailed)
+			So(eventTypes(events), ShouldResemble, []EventType{EventStarting, EventStartFailed})
+			So(r.Errors(), ShouldEqual, 1)
+		})
+
+		Convey("and when it is stopped, it is Stopped", func() {
+			r.Stop()
+			So(r.State(), ShouldEqual, StateStopped)
+		})
+	})
+
+	Convey("When an autorestarting Head of a file that isn't executable is run", t, func() {
+		path := filepath.Join(t.TempDir(), "noexec")
+		So(os.WriteFile(path, []byte("#!/bin/sh\n"), 0600), ShouldBeNil)
+		r := New(path, []string{}, errorChan)
+		r.Autorestart(true)
+		r.RestartDelay = 50 * time.Millisecond
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+
+		Convey("it is Failed, and not restarted", func() {
+			So(r.State(), ShouldEqual, StateFailed)
+			So(r.Restarts(), ShouldEqual, 0)
+		})
+	})
+
+}
@@ -0,0 +1,124 @@
+package head
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// State is the lifecycle state of a Head
+type State string
+
+// States
+const (
+	// StateInit is a Head that has never run
+	StateInit = State("init")
+	// StateStarting is a Head whose process is being started, or, with a ReadyProbe or
+	// ReadyPattern, has started and isn't ready yet
+	StateStarting = State("starting")
+	// StateRunning is a Head whose process is running, and has no readiness checks
+	StateRunning = State("running")
+	// StateReady is a Head whose process is running, and has passed its ReadyProbe or
+	// matched its ReadyPattern
+	StateReady = State("ready")
+	// StateBackoff is a Head waiting to restart its process, after RestartDelay, the
+	// RestartPolicy, or a tripped breaker's BreakerCooldown
+	StateBackoff = State("backoff")
+	// StateStopping is a Head whose process has been told to stop via Stop()
+	StateStopping = State("stopping")
+	// StateStopped is a Head whose process has exited, and won't be restarted
+	StateStopped = State("stopped")
+	// StateFailed is a Head that won't be restarted until its tripped breaker is Reset(), or
+	// that failed to start its process for a reason that retrying won't fix
+	StateFailed = State("failed")
+	// StatePaused is a Head whose process has been paused via Pause()
+	StatePaused = State("paused")
+)
+
+// transitions are the States each State may move to
+var transitions = map[State][]State{
+	StateInit:     {StateStarting, StateStopped},
+	StateStarting: {StateRunning, StateReady, StatePaused, StateBackoff, StateFailed, StateStopping, StateStopped},
+	StateRunning:  {StateReady, StatePaused, StateBackoff, StateFailed, StateStopping, StateStopped},
+	StateReady:    {StatePaused, StateBackoff, StateFailed, StateStopping, StateStopped},
+	StatePaused:   {StateStarting, StateRunning, StateReady, StateBackoff, StateFailed, StateStopping, StateStopped},
+	StateBackoff:  {StateStarting, StateFailed, StateStopping, StateStopped},
+	StateFailed:   {StateStarting, StateBackoff, StateStopping, StateStopped},
+	StateStopping: {StateStopped},
+	StateStopped:  {StateStarting},
+}
+
+// CanTransition returns true if a Head in this State may move to the State to
+func (s State) CanTransition(to State) bool {
+	return slices.Contains(transitions[s], to)
+}
+
+// running returns true if the process of a Head in this State is running, and isn't paused
+func (s State) running() bool {
+	return s == StateRunning || s == StateReady
+}
+
+// Transition is a change in the State of a Head
+type Transition struct {
+	// From is the State before
+	From State
+	// To is the State after
+	To State
+	// Time is when the Transition happened
+	Time time.Time
+}
+
+// String returns the Transition in a line
+func (t Transition) String() string {
+	return fmt.Sprintf("%s %s -> %s", t.Time.Format(time.RFC3339Nano), t.From, t.To)
+}
+
+// State returns the current State of the Head
+func (r *Head) State() State {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	return r.state
+}
+
+// Transitions returns the most recent Transitions of the Head, oldest first, up to
+// TransitionLogSize.
+func (r *Head) Transitions() []Transition {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+	return slices.Clone(r.transitions)
+}
+
+// setState moves the Head to the State, if that is a valid transition, returning false
+// if it isn't. A paused Head stays paused, and will resume to the State instead, unless
+// it is stopping.
+func (r *Head) setState(to State) bool {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	if r.state == StatePaused && to != StateStopping && to != StateStopped {
+		if !StatePaused.CanTransition(to) {
+			return false
+		}
+		r.resumeState = to
+		return true
+	}
+	return r.transition(to)
+}
+
+// transition moves the Head to the State, if that is a valid transition, logging it.
+// The stateLock must be held.
+func (r *Head) transition(to State) bool {
+	if r.state == to {
+		return true
+	} else if !r.state.CanTransition(to) {
+		r.DebugOut.Printf("%s: invalid state transition %s -> %s\n", r.ID, r.state, to)
+		return false
+	}
+
+	r.transitions = append(r.transitions, Transition{From: r.state, To: to, Time: time.Now()})
+	if r.TransitionLogSize >= 0 && len(r.transitions) > r.TransitionLogSize {
+		r.transitions = r.transitions[len(r.transitions)-r.TransitionLogSize:]
+	}
+	r.state = to
+	return true
+}
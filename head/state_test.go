@@ -0,0 +1,119 @@
+package head
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// transitionsTo returns the To States of the Transitions
+func transitionsTo(ts []Transition) []State {
+	var states []State
+	for _, t := range ts {
+		states = append(states, t.To)
+	}
+	return states
+}
+
+func Test_StateCanTransition(t *testing.T) {
+	Convey("When States are transitioned, only valid transitions are allowed", t, func() {
+		So(StateInit.CanTransition(StateStarting), ShouldBeTrue)
+		So(StateInit.CanTransition(StateRunning), ShouldBeFalse)
+		So(StateStarting.CanTransition(StateReady), ShouldBeTrue)
+		So(StateRunning.CanTransition(StatePaused), ShouldBeTrue)
+		So(StateBackoff.CanTransition(StatePaused), ShouldBeFalse)
+		So(StateStopping.CanTransition(StateStopped), ShouldBeTrue)
+		So(StateStopping.CanTransition(StateBackoff), ShouldBeFalse)
+		So(StateStopped.CanTransition(StateRunning), ShouldBeFalse)
+	})
+
+	Convey("When a Head is moved to an invalid State, it is refused", t, func() {
+		r := New("sleep", []string{"30"}, make(chan error, 1))
+		So(r.setState(StateReady), ShouldBeFalse)
+		So(r.State(), ShouldEqual, StateInit)
+		So(r.Transitions(), ShouldBeEmpty)
+	})
+}
+
+func Test_HeadState(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head has never run, and is stopped, it is stopped", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		So(r.State(), ShouldEqual, StateInit)
+
+		r.Stop()
+		So(r.State(), ShouldEqual, StateStopped)
+		So(transitionsTo(r.Transitions()), ShouldResemble, []State{StateStopped})
+	})
+
+	Convey("When a Head is run, and stopped", t, func() {
+		r := New("sleep", []string{"30"}, errorChan)
+		r.StopTimeout = 0
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		So(r.State(), ShouldEqual, StateRunning)
+		So(r.Run(), ShouldBeZeroValue)
+
+		r.Stop()
+		r.Wait()
+
+		Convey("it moves through the States to stopped", func() {
+			So(r.State(), ShouldEqual, StateStopped)
+
+			ts := r.Transitions()
+			So(transitionsTo(ts), ShouldResemble, []State{StateStarting, StateRunning, StateStopping, StateStopped})
+			So(ts[0].From, ShouldEqual, StateInit)
+			So(ts[0].Time, ShouldHappenBefore, ts[3].Time)
+			So(ts[0].String(), ShouldContainSubstring, "init -> starting")
+		})
+	})
+
+	Convey("When an autorestarting Head exits, it backs off before restarting", t, func() {
+		r := New("false", []string{}, errorChan)
+		r.Autorestart(true)
+		r.RestartDelay = 200 * time.Millisecond
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		So(r.State(), ShouldEqual, StateBackoff)
+		So(transitionsTo(r.Transitions()), ShouldResemble, []State{StateStarting, StateRunning, StateBackoff})
+	})
+
+	Convey("When a Head has a small TransitionLogSize, only the most recent Transitions are kept", t, func() {
+		r := New("false", []string{}, errorChan)
+		r.Autorestart(true)
+		r.TransitionLogSize = 3
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		So(r.Transitions(), ShouldHaveLength, 3)
+	})
+
+	Convey("When a Head has a negative TransitionLogSize, all of the Transitions are kept", t, func() {
+		r := New("false", []string{}, errorChan)
+		r.Autorestart(true)
+		r.TransitionLogSize = -1
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		So(len(r.Transitions()), ShouldBeGreaterThan, 3)
+	})
+
+	Convey("When a Head has a TransitionLogSize of 0, no Transitions are kept", t, func() {
+		r := New("false", []string{}, errorChan)
+		r.Autorestart(true)
+		r.TransitionLogSize = 0
+		defer r.Stop()
+
+		r.Run()
+		time.Sleep(100 * time.Millisecond)
+		So(r.Transitions(), ShouldBeEmpty)
+	})
+}
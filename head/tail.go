@@ -0,0 +1,77 @@
+package head
+
+import "sort"
+
+// lineRing is a fixed-size ring of the most recent Lines
+type lineRing struct {
+	lines []Line
+	next  int
+	full  bool
+}
+
+// add puts the Line in the ring, replacing the oldest if it is full
+func (lr *lineRing) add(l Line) {
+	lr.lines[lr.next] = l
+	lr.next = (lr.next + 1) % len(lr.lines)
+	if lr.next == 0 {
+		lr.full = true
+	}
+}
+
+// last returns up to the n most recent Lines, oldest first
+func (lr *lineRing) last(n int) []Line {
+	var lines []Line
+	if lr.full {
+		lines = append(lines, lr.lines[lr.next:]...)
+	}
+	lines = append(lines, lr.lines[:lr.next]...)
+	if n >= 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// Tail returns up to the n most recent Lines the Head's processes output on the stream, oldest
+// first, or of both streams if stream is "". Up to TailSize Lines of each stream are kept, across
+// restarts.
+func (r *Head) Tail(n int, stream Stream) []Line {
+	r.tailLock.Lock()
+	defer r.tailLock.Unlock()
+
+	if stream != "" {
+		if lr, ok := r.tails[stream]; ok {
+			return lr.last(n)
+		}
+		return nil
+	}
+
+	var lines []Line
+	for _, lr := range r.tails {
+		lines = append(lines, lr.last(n)...)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+	if n >= 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// tail keeps the Line for Tail, dropping the oldest of its Stream if TailSize is exceeded
+func (r *Head) tail(l Line) {
+	r.tailLock.Lock()
+	defer r.tailLock.Unlock()
+
+	if r.TailSize <= 0 {
+		return
+	}
+	if r.tails == nil {
+		r.tails = make(map[Stream]*lineRing)
+	}
+	lr, ok := r.tails[l.Stream]
+	if !ok || len(lr.lines) != r.TailSize {
+		// New, or resized: start over
+		lr = &lineRing{lines: make([]Line, r.TailSize)}
+		r.tails[l.Stream] = lr
+	}
+	lr.add(l)
+}
@@ -0,0 +1,86 @@
+package head
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// lineStrings returns the Bytes of the Lines as strings
+func lineStrings(lines []Line) []string {
+	var s []string
+	for _, l := range lines {
+		s = append(s, string(l.Bytes))
+	}
+	return s
+}
+
+func Test_ToStream(t *testing.T) {
+	Convey("When streams are parsed, stdout and stderr are known, and nothing else is", t, func() {
+		s, err := ToStream("stdout")
+		So(err, ShouldBeNil)
+		So(s, ShouldEqual, StreamStdout)
+		s, err = ToStream("stderr")
+		So(err, ShouldBeNil)
+		So(s, ShouldEqual, StreamStderr)
+		_, err = ToStream("stdin")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func Test_HeadTail(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head that outputs to stdout and stderr has run", t, func() {
+		r := BashDashC("echo one; sleep 0.05; echo two >&2; sleep 0.05; echo three; sleep 0.05; echo four >&2", errorChan)
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+
+		Convey("its Tail has the lines of both, in order", func() {
+			lines := r.Tail(-1, "")
+			So(lineStrings(lines), ShouldResemble, []string{"one", "two", "three", "four"})
+			So(lines[0].Stream, ShouldEqual, StreamStdout)
+			So(lines[1].Stream, ShouldEqual, StreamStderr)
+			So(lines[0].Time, ShouldHappenBefore, lines[3].Time)
+		})
+
+		Convey("its Tail of a stream has the lines of just that stream", func() {
+			So(lineStrings(r.Tail(-1, StreamStdout)), ShouldResemble, []string{"one", "three"})
+			So(lineStrings(r.Tail(-1, StreamStderr)), ShouldResemble, []string{"two", "four"})
+		})
+
+		Convey("its Tail of n has the n most recent lines", func() {
+			So(lineStrings(r.Tail(3, "")), ShouldResemble, []string{"two", "three", "four"})
+			So(lineStrings(r.Tail(1, StreamStdout)), ShouldResemble, []string{"three"})
+			So(r.Tail(0, ""), ShouldBeEmpty)
+		})
+	})
+
+	Convey("When a Head with a small TailSize outputs more lines than it keeps over two runs", t, func() {
+		r := BashDashC("for i in 1 2 3; do echo $i; done", errorChan)
+		r.TailSize = 4
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+		r.Run()
+		r.Wait()
+
+		Convey("its Tail has only the most recent TailSize lines, across runs", func() {
+			So(r.History(), ShouldHaveLength, 2)
+			So(lineStrings(r.Tail(-1, StreamStdout)), ShouldResemble, []string{"3", "1", "2", "3"})
+		})
+	})
+
+	Convey("When a Head with TailSize 0 has run, its Tail is empty", t, func() {
+		r := BashDashC("echo one", errorChan)
+		r.TailSize = 0
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+		So(r.Tail(-1, ""), ShouldBeEmpty)
+	})
+}
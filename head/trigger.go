@@ -0,0 +1,196 @@
+package head
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrTriggered is the cause of a process being killed by a Trigger with TriggerRestart
+var ErrTriggered = errors.New("output trigger fired")
+
+// TriggerAction is what a Trigger does when it fires
+type TriggerAction string
+
+// TriggerActions
+const (
+	// TriggerAlert only emits the EventTriggered every firing Trigger does
+	TriggerAlert = TriggerAction("alert")
+	// TriggerRestart kills the process, which is restarted subject to Autorestart
+	TriggerRestart = TriggerAction("restart")
+	// TriggerStop stops the Head, as Stop() does
+	TriggerStop = TriggerAction("stop")
+	// TriggerSignal sends the Trigger's Signal to the process
+	TriggerSignal = TriggerAction("signal")
+	// TriggerWrite writes the Trigger's Input to the process' stdin
+	TriggerWrite = TriggerAction("write")
+)
+
+// ToTriggerAction returns the TriggerAction of the string, or an error if it is not known
+func ToTriggerAction(s string) (TriggerAction, error) {
+	switch a := TriggerAction(s); a {
+	case TriggerAlert, TriggerRestart, TriggerStop, TriggerSignal, TriggerWrite:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown trigger action '%s'", s)
+}
+
+// Trigger is a rule on the output of a Head's processes, that fires its Action when Pattern
+// matches Count lines within Window. Every firing emits an EventTriggered, and starts the
+// count over. Counts also start over with each run.
+type Trigger struct {
+	// Name identifies the Trigger in its Events
+	Name string
+	// Pattern is matched against each line of output
+	Pattern *regexp.Regexp
+	// Stream is the output Pattern is matched against, or both if ""
+	Stream Stream
+	// Count is the number of matching lines that fire the Trigger. Default 1
+	Count int
+	// Window is the duration Count matching lines must be within. If 0, forever
+	Window time.Duration
+	// Action is what the Trigger does when it fires
+	Action TriggerAction
+	// Signal is the signal sent by TriggerSignal
+	Signal os.Signal
+	// Input is what TriggerWrite writes to stdin, as-is
+	Input string
+}
+
+// String returns the Trigger's Name, or its Pattern if it has no Name
+func (t Trigger) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("/%s/", t.Pattern)
+}
+
+// triggerRun is the state of a Head's Triggers during a run of a process
+type triggerRun struct {
+	r        *Head
+	name     string
+	triggers []Trigger
+	kill     func(error)
+	hits     [][]time.Time // times of recent matches, per Trigger
+	pid      int           // of the run's process while it runs, for TriggerSignal
+	running  chan struct{} // closed once the process has started, or failed to
+	once     sync.Once
+	lock     sync.Mutex
+}
+
+// newTriggerRun returns a triggerRun of the Head's Triggers for the run, or nil if there are
+// none. kill ends the run with a cause.
+func (r *Head) newTriggerRun(name string, kill func(error)) *triggerRun {
+	if len(r.Triggers) == 0 {
+		return nil
+	}
+	return &triggerRun{
+		r:        r,
+		name:     name,
+		triggers: r.Triggers,
+		kill:     kill,
+		hits:     make([][]time.Time, len(r.Triggers)),
+		running:  make(chan struct{}),
+	}
+}
+
+// started records the PID of the run's process, once it has started
+func (tr *triggerRun) started(pid int) {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+
+	tr.pid = pid
+	tr.once.Do(func() { close(tr.running) })
+}
+
+// exited records that the run's process is gone, or never started
+func (tr *triggerRun) exited() {
+	tr.started(0)
+}
+
+// signal sends the signal to the run's process, and never to that of a later run,
+// waiting for it to start if the Trigger matched before we knew its PID
+func (tr *triggerRun) signal(sig os.Signal) error {
+	s, err := allowedSignal(sig)
+	if err != nil {
+		return err
+	}
+
+	<-tr.running
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+
+	if tr.pid == 0 {
+		return fmt.Errorf("process has exited")
+	}
+	return tr.r.sendSignal(tr.pid, s)
+}
+
+// check matches the Line against each Trigger, firing those that reach their Count
+func (tr *triggerRun) check(l Line) {
+	for i, t := range tr.triggers {
+		if t.Pattern == nil || (t.Stream != "" && t.Stream != l.Stream) || !t.Pattern.Match(l.Bytes) {
+			continue
+		}
+		if tr.hit(i, l.Time) {
+			// Firing may block on the process, which may be blocked on its output.
+			go tr.fire(t, l)
+		}
+	}
+}
+
+// hit counts a match of the Trigger at index i, returning true if it fires
+func (tr *triggerRun) hit(i int, at time.Time) bool {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+
+	t := tr.triggers[i]
+	hits := append(tr.hits[i], at)
+	if t.Window > 0 {
+		for len(hits) > 0 && at.Sub(hits[0]) > t.Window {
+			hits = hits[1:]
+		}
+	}
+	if len(hits) < max(t.Count, 1) {
+		tr.hits[i] = hits
+		return false
+	}
+	tr.hits[i] = nil
+	return true
+}
+
+// fire emits the EventTriggered for the Trigger, and does its Action
+func (tr *triggerRun) fire(t Trigger, l Line) {
+	r := tr.r
+	r.DebugOut.Printf("%s/%s Trigger %s fired: %s\n", tr.name, r.ID, t, t.Action)
+	r.emit(Event{
+		Type:     EventTriggered,
+		Name:     tr.name,
+		PID:      l.PID,
+		ExitCode: -1,
+		Error:    fmt.Errorf("trigger %s (%s) matched %s: %s", t, t.Action, l.Stream, l.Bytes),
+	})
+
+	var err error
+	switch t.Action {
+	case TriggerRestart:
+		tr.kill(fmt.Errorf("%w: %s", ErrTriggered, t))
+	case TriggerStop:
+		r.Stop()
+	case TriggerSignal:
+		if t.Signal == nil {
+			err = fmt.Errorf("no signal")
+		} else {
+			err = tr.signal(t.Signal)
+		}
+	case TriggerWrite:
+		_, err = io.WriteString(r, t.Input)
+	}
+	if err != nil {
+		r.errorHandler(fmt.Errorf("%s/%s: 'trigger %s' %w", tr.name, r.ID, t, err))
+	}
+}
@@ -0,0 +1,167 @@
+package head
+
+import (
+	"os"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_ToTriggerAction(t *testing.T) {
+	Convey("When trigger actions are parsed, the known ones are, and nonsense is an error", t, func() {
+		for _, s := range []string{"alert", "restart", "stop", "signal", "write"} {
+			a, err := ToTriggerAction(s)
+			So(err, ShouldBeNil)
+			So(a, ShouldEqual, TriggerAction(s))
+		}
+		_, err := ToTriggerAction("explode")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func Test_triggerRunHit(t *testing.T) {
+	Convey("When a Trigger with a Count and Window is hit", t, func() {
+		r := New("true", nil, make(chan error, 1))
+		r.Triggers = []Trigger{{Pattern: regexp.MustCompile("x"), Count: 3, Window: time.Second}}
+		tr := r.newTriggerRun("name", nil)
+		now := time.Now()
+
+		Convey("it fires on the Count-th hit within the Window, and starts over", func() {
+			So(tr.hit(0, now), ShouldBeFalse)
+			So(tr.hit(0, now.Add(100*time.Millisecond)), ShouldBeFalse)
+			So(tr.hit(0, now.Add(200*time.Millisecond)), ShouldBeTrue)
+			So(tr.hit(0, now.Add(300*time.Millisecond)), ShouldBeFalse)
+		})
+
+		Convey("hits older than the Window don't count", func() {
+			So(tr.hit(0, now), ShouldBeFalse)
+			So(tr.hit(0, now.Add(900*time.Millisecond)), ShouldBeFalse)
+			So(tr.hit(0, now.Add(1500*time.Millisecond)), ShouldBeFalse)
+			So(tr.hit(0, now.Add(1600*time.Millisecond)), ShouldBeTrue)
+		})
+	})
+
+	Convey("When a Head has no Triggers, it has no triggerRun", t, func() {
+		r := New("true", nil, make(chan error, 1))
+		So(r.newTriggerRun("name", nil), ShouldBeNil)
+	})
+}
+
+func Test_triggerRunSignal(t *testing.T) {
+	Convey("When a triggerRun's process has exited", t, func() {
+		r := New("true", nil, make(chan error, 1))
+		r.Triggers = []Trigger{{Pattern: regexp.MustCompile("x"), Action: TriggerSignal, Signal: syscall.SIGUSR1}}
+		tr := r.newTriggerRun("name", nil)
+		tr.started(os.Getpid())
+		tr.exited()
+
+		Convey("it signals nothing, even if something else has the PID", func() {
+			So(tr.signal(syscall.SIGUSR1), ShouldNotBeNil)
+		})
+	})
+
+	Convey("When a triggerRun is asked to signal before its process has started", t, func() {
+		r := New("true", nil, make(chan error, 1))
+		r.Triggers = []Trigger{{Pattern: regexp.MustCompile("x"), Action: TriggerSignal, Signal: syscall.SIGUSR1}}
+		tr := r.newTriggerRun("name", nil)
+		errs := make(chan error, 1)
+		go func() { errs <- tr.signal(syscall.SIGUSR1) }()
+
+		Convey("it waits to find out, and signals nothing if it never started", func() {
+			time.Sleep(50 * time.Millisecond)
+			So(errs, ShouldBeEmpty)
+
+			tr.exited()
+			So(<-errs, ShouldNotBeNil)
+		})
+	})
+}
+
+func Test_HeadTriggers(t *testing.T) {
+	errorChan := make(chan error, 10)
+
+	Convey("When a Head has an alert Trigger on stderr with a Count", t, func() {
+		events := make(chan Event, 10)
+		r := BashDashC("for i in 1 2 3 4 5; do echo FATAL $i; echo FATAL $i >&2; done", errorChan)
+		r.Triggers = []Trigger{{Name: "fatal", Pattern: regexp.MustCompile(`^FATAL`), Stream: StreamStderr, Count: 2, Action: TriggerAlert}}
+		r.Subscribe(events)
+		defer r.Stop()
+
+		r.Run()
+		r.Wait()
+		time.Sleep(50 * time.Millisecond) // firing is asynchronous
+
+		Convey("it emits an EventTriggered for every Count matching lines of the Stream", func() {
+			n := 0
+			for _, et := range eventTypes(events) {
+				if et == EventTriggered {
+					n++
+				}
+			}
+			So(n, ShouldEqual, 2)
+		})
+	})
+
+	Convey("When an autorestarting Head has a restart Trigger", t, func() {
+		r := BashDashC("echo starting; sleep 0.1; echo FATAL: connection pool exhausted; sleep 30", errorChan)
+		r.Triggers = []Trigger{{Pattern: regexp.MustCompile(`pool exhausted`), Action: TriggerRestart}}
+		r.Autorestart(true)
+		r.RestartOn = RestartOnAbnormal
+		r.RestartDelay = 50 * time.Millisecond
+		r.StopTimeout = 0
+		defer r.Stop()
+
+		r.Run()
+
+		Convey("the process is killed and restarted", func() {
+			time.Sleep(500 * time.Millisecond)
+			So(r.Restarts(), ShouldBeGreaterThanOrEqualTo, 1)
+			So(r.History()[0].Reason, ShouldEqual, ExitTriggered)
+		})
+	})
+
+	Convey("When a Head has a stop Trigger", t, func() {
+		r := BashDashC("echo bye; sleep 30", errorChan)
+		r.Triggers = []Trigger{{Pattern: regexp.MustCompile(`^bye$`), Action: TriggerStop}}
+		r.Autorestart(true)
+		r.StopTimeout = 0
+		defer r.Stop()
+
+		r.Run()
+
+		Convey("it is stopped", func() {
+			r.Wait()
+			So(r.State(), ShouldEqual, StateStopped)
+			So(r.History()[0].Reason, ShouldEqual, ExitStopped)
+		})
+	})
+
+	Convey("When a Head has a signal Trigger", t, func() {
+		r := BashDashC("trap 'echo got usr1; exit 0' USR1; echo ready; while true; do sleep 0.05; done", errorChan)
+		r.Triggers = []Trigger{{Pattern: regexp.MustCompile(`^ready$`), Action: TriggerSignal, Signal: syscall.SIGUSR1}}
+		defer r.Stop()
+
+		r.Run()
+
+		Convey("the process gets the signal", func() {
+			r.Wait()
+			So(lineStrings(r.Tail(-1, StreamStdout)), ShouldResemble, []string{"ready", "got usr1"})
+		})
+	})
+
+	Convey("When a Head has a write Trigger", t, func() {
+		r := BashDashC("echo 'what now?'; read answer; echo \"told $answer\"", errorChan)
+		r.Triggers = []Trigger{{Pattern: regexp.MustCompile(`\?$`), Action: TriggerWrite, Input: "quit\n"}}
+		defer r.Stop()
+
+		r.Run()
+
+		Convey("the Input is written to the process' stdin", func() {
+			r.Wait()
+			So(lineStrings(r.Tail(-1, StreamStdout)), ShouldResemble, []string{"what now?", "told quit"})
+		})
+	})
+}
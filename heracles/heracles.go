@@ -6,12 +6,35 @@ import (
 	"io"
 	"net"
 	"os"
+	"strings"
 
 	sq "github.com/Hellseher/go-shellquote"
 )
 
+// nagiosCodes are the exit codes for the health levels hydra reports
+var nagiosCodes = map[string]int{
+	"OK":       0,
+	"WARNING":  1,
+	"CRITICAL": 2,
+}
+
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "nagios" {
+		// heracles nagios [head id]
+		os.Exit(nagios(os.Args[2:]))
+	} else if len(os.Args) > 2 && os.Args[1] == "tail" && os.Args[2] != "head" {
+		// heracles tail <head id> [n] [stdout|stderr]
+		os.Exit(tail(os.Args[2:]))
+	} else if len(os.Args) > 2 && os.Args[1] == "connect" {
+		// heracles connect [head] <head id> [input]
+		args := os.Args[2:]
+		if args[0] == "head" {
+			args = args[1:]
+		}
+		os.Exit(attach(args))
+	}
+
 	c, err := net.Dial("unix", "/tmp/hydra.sock")
 	if err != nil {
 		fmt.Printf("Error dialing: %s\n", err)
@@ -36,6 +59,106 @@ func main() {
 
 }
 
+// nagios checks the health of all heads, or the head ID in args, printing the result
+// as a Nagios plugin would, and returning the corresponding exit code.
+func nagios(args []string) int {
+	message := "health heads"
+	if len(args) > 0 {
+		message = sq.Join("health", "head", args[0])
+	}
+
+	c, err := net.Dial("unix", "/tmp/hydra.sock")
+	if err != nil {
+		fmt.Printf("HYDRA UNKNOWN - error dialing: %s\n", err)
+		return 3
+	}
+	defer c.Close()
+
+	if err = write(c, message); err != nil {
+		fmt.Printf("HYDRA UNKNOWN - error writing: %s\n", err)
+		return 3
+	}
+
+	buf := bytes.Buffer{}
+	if _, err = buf.ReadFrom(c); err != nil {
+		fmt.Printf("HYDRA UNKNOWN - error reading: %s\n", err)
+		return 3
+	}
+
+	out := strings.TrimSpace(buf.String())
+	level, _, _ := strings.Cut(out, " ")
+	code, ok := nagiosCodes[level]
+	if !ok {
+		fmt.Printf("HYDRA UNKNOWN - %s\n", out)
+		return 3
+	}
+	fmt.Printf("HYDRA %s\n", out)
+	return code
+}
+
+// tail prints the recent output of the head ID in args, as hydra reports it, returning
+// non-zero if it couldn't.
+func tail(args []string) int {
+	c, err := net.Dial("unix", "/tmp/hydra.sock")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error dialing: %s\n", err)
+		return 1
+	}
+	defer c.Close()
+
+	if err = write(c, sq.Join(append([]string{"tail", "head"}, args...)...)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing: %s\n", err)
+		return 1
+	}
+
+	if _, err = io.Copy(os.Stdout, c); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading from Conn: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// attach opens an interactive session with the head ID in args: what is typed is sent to its
+// stdin, and its output is printed, until stdin is closed (Ctrl-D) to detach, or hydra ends the
+// session. If there is input after the ID, it is sent as the first line, and the session is
+// detached straight away, as a one-shot. It returns non-zero if it couldn't connect.
+func attach(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: heracles connect <head id> [input]\n")
+		return 1
+	}
+
+	c, err := net.Dial("unix", "/tmp/hydra.sock")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error dialing: %s\n", err)
+		return 1
+	}
+	defer c.Close()
+
+	// A session request is one line, and the rest of the conn is the session. Any input
+	// that comes with it is the first line the head gets.
+	if _, err = io.WriteString(c, sq.Join(append([]string{"connect", "head"}, args...)...)+"\n"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing: %s\n", err)
+		return 1
+	}
+
+	go func() {
+		if len(args) == 1 {
+			io.Copy(c, os.Stdin)
+		}
+		// Detach, straight away if that was a one-shot
+		if cw, ok := c.(interface{ CloseWrite() error }); ok {
+			cw.CloseWrite()
+		}
+	}()
+
+	if _, err = io.Copy(os.Stdout, c); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading from Conn: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
 func write(c net.Conn, message string) error {
 	if cw, ok := c.(interface{ CloseWrite() error }); ok {
 		defer cw.CloseWrite()
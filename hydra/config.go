@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/cognusion/prochydra/head"
 	"github.com/spf13/viper"
 )
 
@@ -24,8 +27,29 @@ type HeadConfig struct {
 	StdOutLog string
 	// StdErrLog is where to redirect captures stderr
 	StdErrLog string
-	// RestartDelay specified the duration to wait between restarts
+	// OutputPrefix is a template printed before each line of captured stdout and stderr. Default --outprefix
+	OutputPrefix string
+	// TailSize is the number of recent lines of each of stdout and stderr kept for tail. Default --tailsize (-1 to keep none)
+	TailSize int
+	// RestartDelay specified the duration to wait between restarts, or the first wait if RestartPolicy backs off.
+	// Default --restartdelay, which exponential and jitter treat as 1s if it is also 0
 	RestartDelay time.Duration
+	// RestartPolicy is one of "fixed", "exponential" or "jitter". Default fixed
+	RestartPolicy string
+	// RestartFactor is the multiplier applied to each consecutive backoff. Default 2
+	RestartFactor float64
+	// RestartMaxDelay is the longest a backoff will wait. Default 0 (no maximum)
+	RestartMaxDelay time.Duration
+	// RestartJitter is the largest fraction (0-1) of each jitter backoff that may be randomly removed. Default 1
+	RestartJitter float64
+	// StableUptime is how long Command must run before a backoff starts over. Default 0 (never)
+	StableUptime time.Duration
+	// RestartOn is one of "always", "on-failure", "on-abnormal" or "never", and refines Autorestart. Default always
+	RestartOn string
+	// SuccessExitCodes are exit codes, in addition to 0, that are considered a clean exit for RestartOn
+	SuccessExitCodes []int
+	// RestartPrevent are exit codes (e.g. "3") or signal names (e.g. "TERM") that will never be restarted
+	RestartPrevent []string
 	// MaxPSS specifies the maximum PSS size a process may have before being killed
 	MaxPSS int64
 	// UID is the uid to run as
@@ -40,8 +64,67 @@ type HeadConfig struct {
 	RPMWarnOver interface{}
 	// RPMCritOver sets the HWM for restarts-per-minute, before a Critical is set in the healthcheck. Default 2
 	RPMCritOver interface{}
+	// ErrorsWarnOver sets the HWM for cumulative errors, before a Warning is set in the healthcheck. Default nil (off)
+	ErrorsWarnOver interface{}
+	// ErrorsCritOver sets the HWM for cumulative errors, before a Critical is set in the healthcheck. Default nil (off)
+	ErrorsCritOver interface{}
+	// Breaker is whether to stop restarting Command when its restarts-per-minute exceed RPMCritOver
+	Breaker bool
+	// BreakerCooldown is the duration Breaker stops restarting Command for. Default 0 (until reset)
+	BreakerCooldown time.Duration
 	// Timeout is a duration after which the process running is stopped, subject to  Autorestart
 	Timeout time.Duration
+	// StopSignal is the name or number of the signal sent to stop the process, e.g. "TERM" or "INT". Default TERM
+	StopSignal string
+	// StopTimeout is the duration to wait after StopSignal before the process is killed, 0 to kill it
+	// immediately. Default nil (--stoptimeout)
+	StopTimeout *time.Duration
+	// ProcessGroup is whether to run Command in its own process group, so stops and kills reach all of its children
+	ProcessGroup bool
+	// PTY is whether to run Command on a pseudo-terminal instead of pipes, for programs that need a TTY.
+	// Its stdout and stderr are combined
+	PTY bool
+	// PTYRows is the number of rows of the PTY window. Default 24
+	PTYRows uint16
+	// PTYCols is the number of columns of the PTY window. Default 80
+	PTYCols uint16
+	// ReadyPattern is a regular expression that marks Command ready when a line of its stdout or stderr matches
+	ReadyPattern string
+	// ReadyTCP is a localhost port that marks Command ready when it accepts a connection
+	ReadyTCP int
+	// ReadyFile is a path that marks Command ready when it exists
+	ReadyFile string
+	// ReadyExec is a command that marks Command ready when it exits 0
+	ReadyExec string
+	// ReadyInterval is the duration between ReadyTCP, ReadyFile or ReadyExec probes. Default 1s
+	ReadyInterval time.Duration
+	// LiveTCP is a localhost port that must accept connections for Command to be considered alive
+	LiveTCP int
+	// LiveHTTP is a localhost port that must answer a GET of LiveHTTPPath with a 2xx or 3xx for Command to be considered alive
+	LiveHTTP int
+	// LiveHTTPPath is the path requested from LiveHTTP. Default /
+	LiveHTTPPath string
+	// LiveExec is a command that must exit 0 for Command to be considered alive
+	LiveExec string
+	// LiveInterval is the duration between LiveTCP, LiveHTTP or LiveExec probes. Default 10s
+	LiveInterval time.Duration
+	// LiveTimeout is the duration a liveness probe may take before it fails. Default 1s
+	LiveTimeout time.Duration
+	// LiveFailures is the number of consecutive liveness probe failures before Command is killed. Default 3
+	LiveFailures int
+	// LiveDelay is the duration to wait after Command starts before the first liveness probe. Default 0
+	LiveDelay time.Duration
+	// Triggers are rules on captured stdout and stderr, that fire actions
+	Triggers []TriggerConfig
+	// StdInFrom is the Name of another head whose output is piped to Command's stdin, across restarts of either
+	StdInFrom string
+	// StdInFromStream is "stdout" or "stderr", or "both", the output of StdInFrom piped. Default stdout
+	StdInFromStream string
+	// StdInBuffer is the number of lines buffered while Command isn't reading them. Default 1000
+	StdInBuffer int
+	// StdInPolicy is one of "drop-oldest", "drop-newest" or "block", what happens to lines when StdInBuffer is full.
+	// Default drop-oldest
+	StdInPolicy string
 	// ChildEnvFile is a file of key=value pairs, one per line, that create the environment for the child processes
 	// if unset, the parent environment will be inherhited
 	ChildEnvFile string
@@ -51,6 +134,197 @@ type HeadConfig struct {
 	StdInShellEscapeInput bool
 }
 
+// TriggerConfig is a configuration struct for a head.Trigger
+type TriggerConfig struct {
+	// Name identifies the trigger in events
+	Name string
+	// Pattern is a regular expression matched against each line of output
+	Pattern string
+	// Stream is "stdout" or "stderr" to only match that output. Default both
+	Stream string
+	// Count is the number of matching lines that fire the trigger. Default 1
+	Count int
+	// Window is the duration Count matching lines must be within. Default 0 (forever)
+	Window time.Duration
+	// Action is one of "alert", "restart", "stop", "signal" or "write". Default alert
+	Action string
+	// Signal is the name or number of the signal sent by the "signal" Action
+	Signal string
+	// Input is what the "write" Action writes to stdin, with a newline appended unless StdInNoNL
+	Input string
+}
+
+// GetRestartPolicy returns the head.RestartPolicy described by the HeadConfig, using
+// delay as the fixed or initial delay, or an error if the policy is unknown.
+func (hc *HeadConfig) GetRestartPolicy(delay time.Duration) (head.RestartPolicy, error) {
+	exponential := head.ExponentialRestart{
+		Base:   delay,
+		Factor: hc.RestartFactor,
+		Max:    hc.RestartMaxDelay,
+	}
+
+	switch strings.ToLower(hc.RestartPolicy) {
+	case "", "fixed":
+		return &head.FixedRestart{Wait: delay}, nil
+	case "exponential":
+		return &exponential, nil
+	case "jitter":
+		return &head.JitterRestart{ExponentialRestart: exponential, Jitter: hc.RestartJitter}, nil
+	default:
+		return nil, fmt.Errorf("unknown restart policy '%s'", hc.RestartPolicy)
+	}
+}
+
+// GetReadyProbe returns the head.Probe described by ReadyTCP, ReadyFile or ReadyExec,
+// nil if there isn't one, or an error if there is more than one.
+func (hc *HeadConfig) GetReadyProbe() (head.Probe, error) {
+	var probes []head.Probe
+	if hc.ReadyTCP > 0 {
+		probes = append(probes, head.TCPProbe{Port: hc.ReadyTCP})
+	}
+	if hc.ReadyFile != "" {
+		probes = append(probes, head.FileProbe{Path: hc.ReadyFile})
+	}
+	if hc.ReadyExec != "" {
+		command, args, err := CommandSplit(hc.ReadyExec)
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, head.ExecProbe{Command: command, Args: args})
+	}
+
+	return oneProbe(probes, "ReadyTCP, ReadyFile or ReadyExec")
+}
+
+// GetLiveProbe returns the head.Probe described by LiveTCP, LiveHTTP or LiveExec,
+// nil if there isn't one, or an error if there is more than one.
+func (hc *HeadConfig) GetLiveProbe() (head.Probe, error) {
+	var probes []head.Probe
+	if hc.LiveTCP > 0 {
+		probes = append(probes, head.TCPProbe{Port: hc.LiveTCP})
+	}
+	if hc.LiveHTTP > 0 {
+		probes = append(probes, head.HTTPProbe{Port: hc.LiveHTTP, Path: hc.LiveHTTPPath})
+	}
+	if hc.LiveExec != "" {
+		command, args, err := CommandSplit(hc.LiveExec)
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, head.ExecProbe{Command: command, Args: args})
+	}
+
+	return oneProbe(probes, "LiveTCP, LiveHTTP or LiveExec")
+}
+
+// GetTriggers returns the head.Triggers described by Triggers, or an error if one is invalid.
+func (hc *HeadConfig) GetTriggers() ([]head.Trigger, error) {
+	var triggers []head.Trigger
+	for i, tc := range hc.Triggers {
+		t := head.Trigger{
+			Name:   tc.Name,
+			Count:  tc.Count,
+			Window: tc.Window,
+			Action: head.TriggerAlert,
+			Input:  tc.Input,
+		}
+		if t.Name == "" {
+			t.Name = strconv.Itoa(i)
+		}
+
+		var err error
+		if t.Pattern, err = regexp.Compile(tc.Pattern); err != nil || tc.Pattern == "" {
+			return nil, fmt.Errorf("trigger %s: bad pattern '%s': %v", t.Name, tc.Pattern, err)
+		}
+		if tc.Stream != "" {
+			if t.Stream, err = head.ToStream(tc.Stream); err != nil {
+				return nil, fmt.Errorf("trigger %s: %w", t.Name, err)
+			}
+		}
+		if tc.Action != "" {
+			if t.Action, err = head.ToTriggerAction(strings.ToLower(tc.Action)); err != nil {
+				return nil, fmt.Errorf("trigger %s: %w", t.Name, err)
+			}
+		}
+		switch t.Action {
+		case head.TriggerSignal:
+			if t.Signal, err = head.ParseSignal(tc.Signal); err != nil {
+				return nil, fmt.Errorf("trigger %s: %w", t.Name, err)
+			}
+		case head.TriggerWrite:
+			if !hc.StdInNoNL {
+				t.Input += "\n"
+			}
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, nil
+}
+
+// GetPipe returns a head.Pipe to the head described by StdInFromStream, StdInBuffer and StdInPolicy,
+// or an error if one is invalid.
+func (hc *HeadConfig) GetPipe(to *head.Head) (*head.Pipe, error) {
+	var (
+		stream = head.StreamStdout
+		size   = 1000
+		policy = head.PipeDropOldest
+		err    error
+	)
+	switch hc.StdInFromStream {
+	case "":
+	case "both":
+		stream = ""
+	default:
+		if stream, err = head.ToStream(hc.StdInFromStream); err != nil {
+			return nil, err
+		}
+	}
+	if hc.StdInBuffer > 0 {
+		size = hc.StdInBuffer
+	}
+	if hc.StdInPolicy != "" {
+		if policy, err = head.ToPipePolicy(strings.ToLower(hc.StdInPolicy)); err != nil {
+			return nil, err
+		}
+	}
+	return head.NewPipe(to, stream, size, policy), nil
+}
+
+// oneProbe returns the only probe, nil if there are none, or an error naming the
+// options if there is more than one.
+func oneProbe(probes []head.Probe, options string) (head.Probe, error) {
+	switch len(probes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return probes[0], nil
+	default:
+		return nil, fmt.Errorf("only one of %s may be set", options)
+	}
+}
+
+// GetRestartPrevent returns the exit codes and signals described by RestartPrevent,
+// or an error if a signal is unknown.
+func (hc *HeadConfig) GetRestartPrevent() ([]int, []os.Signal, error) {
+	var (
+		codes   []int
+		signals []os.Signal
+	)
+	for _, p := range hc.RestartPrevent {
+		if code, err := strconv.Atoi(p); err == nil {
+			codes = append(codes, code)
+			continue
+		}
+
+		sig, err := head.ParseSignal(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		signals = append(signals, sig)
+	}
+	return codes, signals, nil
+}
+
 // ValueSwitch returns -1 if the v is nil or not an int, otherwise returns the int value
 func ValueSwitch(v interface{}) int {
 	if v == nil {
@@ -123,6 +397,7 @@ func loadDefaults(v *viper.Viper) error {
 	v.SetDefault("logsize", 100)                 // Maximum size, in MB, that the currently log can be before rolling
 	v.SetDefault("logbackups", 3)                // Maximum number of rolled logs to keep
 	v.SetDefault("logage", 28)                   // Maximum age, in days, to keep rolled logs
+	v.SetDefault("logformat", LogFormatText)     // Format of logs and child output: 'text', or 'json' for one JSON object per line
 
 	// Container for head definitions
 	v.SetDefault("heads", make([]interface{}, 0))
@@ -137,6 +412,11 @@ func loadDefaults(v *viper.Viper) error {
 	v.SetDefault("maxpss", int64(0))               // Maximum PSS (in MB) each process is allowed before being killed
 	v.SetDefault("autorestart", false)             // Enable autorestarts. Set --restartdelay to sleep in between
 	v.SetDefault("restartdelay", time.Duration(0)) // Duration of wait between restarts, e.g. "1s" or "100ms" (0 for no delay)
+	v.SetDefault("stopsignal", "TERM")             // Signal to send to processes when stopping them
+	v.SetDefault("stoptimeout", 10*time.Second)    // Duration to wait after the stop signal before killing (0 to kill immediately)
+	v.SetDefault("processgroup", false)            // Run each process in its own process group
+	v.SetDefault("outprefix", "")                  // Template printed before each line of child output, e.g. "[{headname}/{instance}] "
+	v.SetDefault("tailsize", 100)                  // Number of recent lines of each of stdout and stderr to keep per head, for tail (0 to keep none)
 
 	return nil
 }
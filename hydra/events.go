@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/cognusion/prochydra/head"
+)
+
+// EventLog is a goro-safe, bounded log of the most recent head.Events
+type EventLog struct {
+	size   int
+	events []head.Event
+	lock   sync.Mutex
+}
+
+// NewEventLog returns an EventLog that keeps the most recent size Events
+func NewEventLog(size int) *EventLog {
+	return &EventLog{
+		size: size,
+	}
+}
+
+// Add appends the Event to the log, dropping the oldest if the log is full
+func (l *EventLog) Add(e head.Event) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.events = append(l.events, e)
+	if len(l.events) > l.size {
+		l.events = l.events[len(l.events)-l.size:]
+	}
+}
+
+// Events returns the logged Events for the head ID, or all of them if id is empty, oldest first
+func (l *EventLog) Events(id string) []head.Event {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var events []head.Event
+	for _, e := range l.events {
+		if id == "" || e.HeadID == id {
+			events = append(events, e)
+		}
+	}
+	return events
+}
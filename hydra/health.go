@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cognusion/prochydra/head"
+)
+
+// Health is a healthcheck level, ordered by severity, valued as Nagios exit codes
+type Health int
+
+// Healths
+const (
+	HealthOK Health = iota
+	HealthWarning
+	HealthCritical
+	HealthUnknown
+)
+
+// String returns the Nagios name of the Health
+func (h Health) String() string {
+	switch h {
+	case HealthOK:
+		return "OK"
+	case HealthWarning:
+		return "WARNING"
+	case HealthCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// HeadHealth is the healthcheck of a head, and the reasons it isn't OK
+type HeadHealth struct {
+	ID      string
+	Name    string
+	Health  Health
+	Reasons []string
+}
+
+// String returns the HeadHealth in a line
+func (hh *HeadHealth) String() string {
+	s := hh.ID
+	if hh.Name != "" {
+		s += fmt.Sprintf(" (%s)", hh.Name)
+	}
+	s += ": " + hh.Health.String()
+	if len(hh.Reasons) > 0 {
+		s += " - " + strings.Join(hh.Reasons, ", ")
+	}
+	return s
+}
+
+// raise worsens the Health to level, if it isn't already worse, and notes the reason
+func (hh *HeadHealth) raise(level Health, format string, a ...interface{}) {
+	if level > hh.Health {
+		hh.Health = level
+	}
+	hh.Reasons = append(hh.Reasons, fmt.Sprintf(format, a...))
+}
+
+// over raises the Health if value is over the "<what>CritOver" or "<what>WarnOver" Values of the head
+func (hh *HeadHealth) over(h *head.Head, what string, value uint64) {
+	if crit, ok := ValueBomb(h.Values.Load(what + "CritOver")); ok && value > crit {
+		hh.raise(HealthCritical, "%s %d > %d", strings.ToLower(what), value, crit)
+	} else if warn, ok := ValueBomb(h.Values.Load(what + "WarnOver")); ok && value > warn {
+		hh.raise(HealthWarning, "%s %d > %d", strings.ToLower(what), value, warn)
+	}
+}
+
+// CheckHead returns the HeadHealth of the head, from its restart, restarts-per-minute
+// and error thresholds, and its state
+func CheckHead(h *head.Head) HeadHealth {
+	hh := HeadHealth{ID: h.ID}
+	if name, ok := h.Values.Load("Name"); ok {
+		hh.Name, _ = name.(string)
+	}
+
+	hh.over(h, "Restarts", h.Restarts())
+	hh.over(h, "RPM", h.RestartsPerMinute())
+	hh.over(h, "Errors", h.Errors())
+
+	switch state := h.State(); {
+	case state == head.StateFailed:
+		hh.raise(HealthCritical, "state %s", state)
+	case h.Tripped():
+		hh.raise(HealthWarning, "breaker tripped")
+	case state == head.StatePaused:
+		hh.raise(HealthWarning, "state %s", state)
+	}
+	return hh
+}
+
+// CheckHeads returns the overall Health, the worst of any head, and the HeadHealth of each head
+func CheckHeads() (Health, []HeadHealth) {
+	var (
+		overall = HealthOK
+		hhs     []HeadHealth
+	)
+	heads.Range(func(k, v interface{}) bool {
+		if h := v.(*head.Head); h != nil {
+			hh := CheckHead(h)
+			if hh.Health > overall {
+				overall = hh.Health
+			}
+			hhs = append(hhs, hh)
+		}
+		return true
+	})
+	sort.Slice(hhs, func(i, j int) bool { return hhs[i].ID < hhs[j].ID })
+	return overall, hhs
+}
+
+// WriteHealth writes a Nagios-style summary line of the overall Health, followed by a
+// line for each HeadHealth
+func WriteHealth(w io.Writer, overall Health, hhs []HeadHealth) {
+	counts := make(map[Health]int)
+	for _, hh := range hhs {
+		counts[hh.Health]++
+	}
+	fmt.Fprintf(w, "%s - %d head(s): %d OK, %d WARNING, %d CRITICAL\n",
+		overall, len(hhs), counts[HealthOK], counts[HealthWarning], counts[HealthCritical])
+	for _, hh := range hhs {
+		fmt.Fprintln(w, hh.String())
+	}
+}
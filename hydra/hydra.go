@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -40,6 +41,8 @@ var (
 	idSeq = sequence.NewWithHashIDLength(0, 14) // idSeq is for IDs
 	seq   = sequence.New(0)                     // seq is for heads to use in macros
 
+	recentEvents = NewEventLog(100) // recentEvents is the last few head events, for the server
+
 	conf *viper.Viper
 	dict dictionary.SimpleDict
 )
@@ -53,6 +56,8 @@ func init() {
 	pflag.String("exec", "", "Command to execute, if singular. Ignores many other options and should only be used for debugging")
 	pflag.Bool("autorestart", false, "Enable autorestarts. Set --restartdelay to sleep in between")
 	pflag.String("restartdelay", "0s", "Duration of wait between restarts, e.g. \"1s\" or \"100ms\"")
+	pflag.String("stopsignal", "TERM", "Signal to send to processes when stopping them, e.g. \"TERM\" or \"INT\"")
+	pflag.String("stoptimeout", "10s", "Duration to wait after the stop signal before killing, e.g. \"10s\" (0 to kill immediately)")
 
 	pflag.String("proto", "unix", "Protocol to use for server connections. Must be empty to disable, or one of 'tcp', 'tcp4', 'tcp6', 'unix'.")
 	pflag.String("address", "/tmp/hydra.sock", "Address for the server to listen to.")
@@ -65,14 +70,18 @@ func init() {
 	pflag.Int("logsize", 100, "Maximum size, in MB, that the current log can be before rolling")
 	pflag.Int("logbackups", 3, "Maximum number of rolled logs to keep")
 	pflag.Int("logage", 28, "Maximum age, in days, to keep rolled logs")
+	pflag.String("outprefix", "", "Template printed before each line of child output, e.g. \"[{headname}/{instance}] \". Also {headid}, {pid}, {run}, {stream} and {time}")
+	pflag.String("logformat", LogFormatText, "Format of logs and child output: 'text', or 'json' for one JSON object per line")
 
 	pflag.Int64("maxpss", 0, "Maximum PSS (in MB) each process is allowed before being killed")
+	pflag.Int("tailsize", 100, "Number of recent lines of each of stdout and stderr to keep per head, for tail (0 to keep none)")
 	pflag.Int("seq", 0, "Integer to start a sequence at. {seq} in a command will be incremented per-command in a head instance (regardless of counts or mutations, starting at this number")
 	pflag.Uint("uid", 0, "Run as uid (0 for current user)")
 	pflag.Uint("gid", 0, "Run as gid (-uid must be set as well) (0 for current group)")
 
 	pflag.Bool("version", false, fmt.Sprintf("Print the version (%s), and then exit", VERSION))
 	pflag.Bool("dashc", false, "Wrap the commands in 'bash -c' instead of running them directly")
+	pflag.Bool("processgroup", false, "Run each process in its own process group, so stopping it stops all of its children too")
 	config := pflag.String("config", "", "Config file to load")
 
 	pflag.Parse()
@@ -97,7 +106,14 @@ func init() {
 	}
 
 	// Set the ErrorOut
-	ErrorOut = GetErrorLog(dict.Replacer(conf.GetString("log")), "[HEAD] ", OutFormat, conf.GetInt("logsize"), conf.GetInt("logbackups"), conf.GetInt("logage"))
+	switch conf.GetString("logformat") {
+	case LogFormatText:
+		ErrorOut = GetErrorLog(dict.Replacer(conf.GetString("log")), "[HEAD] ", OutFormat, conf.GetInt("logsize"), conf.GetInt("logbackups"), conf.GetInt("logage"))
+	case LogFormatJSON:
+		ErrorOut = GetJSONErrorLog(dict.Replacer(conf.GetString("log")), LevelError, conf.GetInt("logsize"), conf.GetInt("logbackups"), conf.GetInt("logage"))
+	default:
+		log.Fatalf("Error: logformat '%s' is not one of '%s' or '%s'\n", conf.GetString("logformat"), LogFormatText, LogFormatJSON)
+	}
 
 	// Set the StdOut & StdErr logs
 	StdOut = GetLog(dict.Replacer(conf.GetString("outlog")), "", 0, conf.GetInt("logsize"), conf.GetInt("logbackups"), conf.GetInt("logage"))
@@ -105,7 +121,11 @@ func init() {
 
 	// Set the DebugOut, maybe
 	if conf.GetBool("debug") {
-		DebugOut = GetErrorLog(dict.Replacer(conf.GetString("log")), "[DEBUG] ", OutFormat, conf.GetInt("logsize"), conf.GetInt("logbackups"), conf.GetInt("logage"))
+		if conf.GetString("logformat") == LogFormatJSON {
+			DebugOut = GetJSONErrorLog(dict.Replacer(conf.GetString("log")), LevelDebug, conf.GetInt("logsize"), conf.GetInt("logbackups"), conf.GetInt("logage"))
+		} else {
+			DebugOut = GetErrorLog(dict.Replacer(conf.GetString("log")), "[DEBUG] ", OutFormat, conf.GetInt("logsize"), conf.GetInt("logbackups"), conf.GetInt("logage"))
+		}
 		conf.DebugTo(DebugOut.Writer()) // belch out the config debug output
 	}
 
@@ -118,6 +138,11 @@ func init() {
 	if conf.GetInt("seq") > 0 {
 		seq = sequence.New(conf.GetInt("seq"))
 	}
+
+	// Validate the stop signal early
+	if _, err := head.ParseSignal(conf.GetString("stopsignal")); err != nil {
+		log.Fatalf("Error parsing stopsignal '%s': %s\n", conf.GetString("stopsignal"), err)
+	}
 }
 
 func main() {
@@ -132,6 +157,7 @@ func main() {
 
 	var (
 		errorChan = make(chan error, 20)
+		eventChan = make(chan head.Event, 100)
 		wg        sync.WaitGroup
 	)
 
@@ -158,6 +184,20 @@ func main() {
 		}
 	}()
 
+	// Fork off the event reader. Failed runs are already reported by the error reader, so
+	// only the events that nothing else reports are errors.
+	go func() {
+		for e := range eventChan {
+			recentEvents.Add(e)
+			switch e.Type {
+			case head.EventBreakerTripped, head.EventTriggered:
+				ErrorOut.Println(e)
+			default:
+				DebugOut.Println(e)
+			}
+		}
+	}()
+
 	var (
 		serverStopChan    chan struct{}
 		serverRequestChan <-chan greek.Request
@@ -231,13 +271,24 @@ func main() {
 		h.DebugOut = DebugOut
 		h.ErrOut = ErrorOut
 		h.StdOut = StdOut
+		if conf.GetString("logformat") == LogFormatJSON {
+			jsonOutput(h, "")
+		}
 		h.Seq = seq
 		h.MaxPSS = conf.GetInt64("maxpss")
+		h.TailSize = conf.GetInt("tailsize")
+		h.OutputPrefix = outputPrefix(conf.GetString("outprefix"), "")
 		h.StdInNoNL = conf.GetBool("nonl")
 		h.StdInShellEscapeInput = conf.GetBool("shellescape")
+		h.StopSignal, _ = head.ParseSignal(conf.GetString("stopsignal"))
+		h.StopTimeout = conf.GetDuration("stoptimeout")
+		h.ProcessGroup = conf.GetBool("processgroup")
+		h.Subscribe(eventChan)
 
 		// Add this head to the list and waitgroup
 		h.ID = idSeq.NextHashID()
+		h.DebugOut = headLog(h.DebugOut, h.ID, "")
+		h.ErrOut = headLog(h.ErrOut, h.ID, "")
 		heads.Store(h.ID, h)
 		wg.Add(1)
 
@@ -260,6 +311,17 @@ func main() {
 		confheads := make([]HeadConfig, len(headcheck.([]interface{})))
 		conf.UnmarshalKey("heads", &confheads)
 
+		// Every head is configured before any are run, so pipes between them can be wired
+		type configured struct {
+			hc        HeadConfig
+			instances []*head.Head
+		}
+		var (
+			configs []configured
+			named   = make(map[string][]*head.Head) // instances, by Name
+			pipes   = make(map[*head.Head]*head.Pipe)
+		)
+
 		// Iterate over the commands
 		for _, hc := range confheads {
 
@@ -286,6 +348,7 @@ func main() {
 			h.Seq = seq
 			h.StdInNoNL = hc.StdInNoNL
 			h.StdInShellEscapeInput = hc.StdInShellEscapeInput
+			h.Subscribe(eventChan)
 
 			if hc.ChildEnvFile != "" {
 				content, err := os.ReadFile(hc.ChildEnvFile)
@@ -312,6 +375,10 @@ func main() {
 				h.StdErr = StdErr
 			}
 
+			if conf.GetString("logformat") == LogFormatJSON {
+				jsonOutput(h, hc.Name)
+			}
+
 			if hc.Autorestart {
 				DebugOut.Printf("\tHeadC Custom Autorestart: %t\n", hc.Autorestart)
 				h.Autorestart(hc.Autorestart)
@@ -326,6 +393,44 @@ func main() {
 				h.RestartDelay = conf.GetDuration("restartdelay")
 			}
 
+			if hc.RestartPolicy != "" {
+				DebugOut.Printf("\tHeadC Custom RestartPolicy: %s\n", hc.RestartPolicy)
+				rp, err := hc.GetRestartPolicy(h.RestartDelay)
+				if err != nil {
+					ErrorOut.Fatalf("Error with RestartPolicy: %s\n", err)
+				}
+				h.RestartPolicy = rp
+			}
+
+			if hc.RestartOn != "" {
+				DebugOut.Printf("\tHeadC Custom RestartOn: %s\n", hc.RestartOn)
+				rc, err := head.ToRestartCondition(hc.RestartOn)
+				if err != nil {
+					ErrorOut.Fatalf("Error with RestartOn: %s\n", err)
+				}
+				h.RestartOn = rc
+			}
+
+			if len(hc.SuccessExitCodes) > 0 {
+				DebugOut.Printf("\tHeadC Custom SuccessExitCodes: %v\n", hc.SuccessExitCodes)
+				h.SuccessExitCodes = hc.SuccessExitCodes
+			}
+
+			if len(hc.RestartPrevent) > 0 {
+				DebugOut.Printf("\tHeadC Custom RestartPrevent: %v\n", hc.RestartPrevent)
+				codes, signals, err := hc.GetRestartPrevent()
+				if err != nil {
+					ErrorOut.Fatalf("Error with RestartPrevent: %s\n", err)
+				}
+				h.RestartPreventExitCodes = codes
+				h.RestartPreventSignals = signals
+			}
+
+			if hc.StableUptime > 0 {
+				DebugOut.Printf("\tHeadC Custom StableUptime: %s\n", hc.StableUptime.String())
+				h.StableUptime = hc.StableUptime
+			}
+
 			if hc.MaxPSS > 0 {
 				DebugOut.Printf("\tHeadC Custom MaxPSS: %d\n", hc.MaxPSS)
 				h.MaxPSS = hc.MaxPSS
@@ -333,6 +438,20 @@ func main() {
 				h.MaxPSS = conf.GetInt64("maxpss")
 			}
 
+			if hc.OutputPrefix != "" {
+				DebugOut.Printf("\tHeadC Custom OutputPrefix: %q\n", hc.OutputPrefix)
+				h.OutputPrefix = outputPrefix(hc.OutputPrefix, hc.Name)
+			} else {
+				h.OutputPrefix = outputPrefix(conf.GetString("outprefix"), hc.Name)
+			}
+
+			if hc.TailSize != 0 {
+				DebugOut.Printf("\tHeadC Custom TailSize: %d\n", hc.TailSize)
+				h.TailSize = hc.TailSize
+			} else {
+				h.TailSize = conf.GetInt("tailsize")
+			}
+
 			if hc.UID > 0 {
 				DebugOut.Printf("\tHeadC Custom UID: %d\n", hc.UID)
 				h.UID = hc.UID
@@ -363,32 +482,230 @@ func main() {
 				h.Values.Store("RestartsWarnOver", ValueSwitch(hc.RestartsWarnOver))
 			}
 
+			if hc.ErrorsCritOver == nil {
+				// Default -1 (off)
+				h.Values.Store("ErrorsCritOver", -1)
+			} else {
+				DebugOut.Printf("\tHeadC Custom ErrorsCritOver: %v\n", hc.ErrorsCritOver)
+				h.Values.Store("ErrorsCritOver", ValueSwitch(hc.ErrorsCritOver))
+			}
+
+			if hc.ErrorsWarnOver == nil {
+				// Default -1 (off)
+				h.Values.Store("ErrorsWarnOver", -1)
+			} else {
+				DebugOut.Printf("\tHeadC Custom ErrorsWarnOver: %v\n", hc.ErrorsWarnOver)
+				h.Values.Store("ErrorsWarnOver", ValueSwitch(hc.ErrorsWarnOver))
+			}
+
+			if hc.RPMCritOver == nil {
+				// Default 2
+				h.Values.Store("RPMCritOver", 2)
+			} else {
+				DebugOut.Printf("\tHeadC Custom RPMCritOver: %v\n", hc.RPMCritOver)
+				h.Values.Store("RPMCritOver", ValueSwitch(hc.RPMCritOver))
+			}
+
+			if hc.RPMWarnOver == nil {
+				// Default 0
+				h.Values.Store("RPMWarnOver", 0)
+			} else {
+				DebugOut.Printf("\tHeadC Custom RPMWarnOver: %v\n", hc.RPMWarnOver)
+				h.Values.Store("RPMWarnOver", ValueSwitch(hc.RPMWarnOver))
+			}
+
+			if hc.Breaker {
+				DebugOut.Printf("\tHeadC Custom Breaker: %t\n", hc.Breaker)
+				if rpm, ok := ValueBomb(h.Values.Load("RPMCritOver")); ok && rpm > 0 {
+					h.BreakerRPM = rpm
+				} else {
+					ErrorOut.Fatalf("Error with Breaker: RPMCritOver must be a positive number\n")
+				}
+			}
+
+			if hc.BreakerCooldown > 0 {
+				DebugOut.Printf("\tHeadC Custom BreakerCooldown: %s\n", hc.BreakerCooldown.String())
+				h.BreakerCooldown = hc.BreakerCooldown
+			}
+
 			if hc.Timeout > 0 {
 				DebugOut.Printf("\tHeadC Custom Timeout: %s\n", hc.Timeout.String())
 				h.Timeout = hc.Timeout
 			}
 
+			if hc.StopSignal != "" {
+				DebugOut.Printf("\tHeadC Custom StopSignal: %s\n", hc.StopSignal)
+				sig, err := head.ParseSignal(hc.StopSignal)
+				if err != nil {
+					ErrorOut.Fatalf("Error parsing StopSignal '%s': %s\n", hc.StopSignal, err)
+				}
+				h.StopSignal = sig
+			} else {
+				h.StopSignal, _ = head.ParseSignal(conf.GetString("stopsignal"))
+			}
+
+			if hc.StopTimeout != nil {
+				DebugOut.Printf("\tHeadC Custom StopTimeout: %s\n", hc.StopTimeout.String())
+				h.StopTimeout = *hc.StopTimeout
+			} else {
+				h.StopTimeout = conf.GetDuration("stoptimeout")
+			}
+
+			if hc.ProcessGroup {
+				DebugOut.Printf("\tHeadC Custom ProcessGroup: %t\n", hc.ProcessGroup)
+				h.ProcessGroup = hc.ProcessGroup
+			} else {
+				h.ProcessGroup = conf.GetBool("processgroup")
+			}
+
+			if hc.PTY {
+				DebugOut.Printf("\tHeadC Custom PTY: %t (%dx%d)\n", hc.PTY, hc.PTYRows, hc.PTYCols)
+				h.PTY = hc.PTY
+				if hc.PTYRows > 0 {
+					h.PTYRows = hc.PTYRows
+				}
+				if hc.PTYCols > 0 {
+					h.PTYCols = hc.PTYCols
+				}
+			}
+
+			if hc.ReadyPattern != "" {
+				DebugOut.Printf("\tHeadC Custom ReadyPattern: %s\n", hc.ReadyPattern)
+				re, err := regexp.Compile(hc.ReadyPattern)
+				if err != nil {
+					ErrorOut.Fatalf("Error parsing ReadyPattern '%s': %s\n", hc.ReadyPattern, err)
+				}
+				h.ReadyPattern = re
+			}
+
+			if hc.ReadyTCP > 0 || hc.ReadyFile != "" || hc.ReadyExec != "" {
+				hc.ReadyFile = dict.Replacer(hc.ReadyFile)
+				hc.ReadyExec = dict.Replacer(hc.ReadyExec)
+				probe, err := hc.GetReadyProbe()
+				if err != nil {
+					ErrorOut.Fatalf("Error with ReadyProbe: %s\n", err)
+				}
+				DebugOut.Printf("\tHeadC Custom ReadyProbe: %s\n", probe)
+				h.ReadyProbe = probe
+			}
+
+			if hc.ReadyInterval > 0 {
+				DebugOut.Printf("\tHeadC Custom ReadyInterval: %s\n", hc.ReadyInterval.String())
+				h.ReadyInterval = hc.ReadyInterval
+			}
+
+			if hc.LiveTCP > 0 || hc.LiveHTTP > 0 || hc.LiveExec != "" {
+				hc.LiveExec = dict.Replacer(hc.LiveExec)
+				probe, err := hc.GetLiveProbe()
+				if err != nil {
+					ErrorOut.Fatalf("Error with LiveProbe: %s\n", err)
+				}
+				DebugOut.Printf("\tHeadC Custom LiveProbe: %s\n", probe)
+				h.LiveProbe = probe
+			}
+
+			if hc.LiveInterval > 0 {
+				DebugOut.Printf("\tHeadC Custom LiveInterval: %s\n", hc.LiveInterval.String())
+				h.LiveInterval = hc.LiveInterval
+			}
+
+			if hc.LiveTimeout > 0 {
+				DebugOut.Printf("\tHeadC Custom LiveTimeout: %s\n", hc.LiveTimeout.String())
+				h.LiveTimeout = hc.LiveTimeout
+			}
+
+			if hc.LiveFailures > 0 {
+				DebugOut.Printf("\tHeadC Custom LiveFailures: %d\n", hc.LiveFailures)
+				h.LiveFailures = hc.LiveFailures
+			}
+
+			if hc.LiveDelay > 0 {
+				DebugOut.Printf("\tHeadC Custom LiveDelay: %s\n", hc.LiveDelay.String())
+				h.LiveDelay = hc.LiveDelay
+			}
+
+			if len(hc.Triggers) > 0 {
+				DebugOut.Printf("\tHeadC Custom Triggers: %d\n", len(hc.Triggers))
+				triggers, err := hc.GetTriggers()
+				if err != nil {
+					ErrorOut.Fatalf("Error parsing triggers: %s\n", err)
+				}
+				h.Triggers = triggers
+			}
+
 			if hc.Name != "" {
 				DebugOut.Printf("\tHeadC Custom Name: %s\n", hc.Name)
 				h.Values.Store("Name", hc.Name)
 			}
 
-			// bookkeeping
-			h.ID = idSeq.NextHashID()
-			heads.Store(h.ID, h)
-			wg.Add(1)
+			if hc.Number > 1 {
+				DebugOut.Printf("\tHeadC Custom Number: %d\n", hc.Number)
+			}
 
-			// Run the head
-			rs := h.Run()
-			DebugOut.Printf("Conf: %s\n", h.String())
-			DebugOut.Printf("Live: %s\n", rs)
+			// Each instance is a Clone of the configured head, so it gets its own ID,
+			// macro expansion and counters.
+			instances := []*head.Head{h}
+			for n := 1; n < hc.Number; n++ {
+				instances = append(instances, h.Clone())
+			}
 
-			// Wait for this head to finish, or not
-			go func(r *head.Head) {
-				defer wg.Done()
-				defer heads.Delete(r.ID)
-				r.Wait()
-			}(h)
+			for _, ih := range instances {
+				// bookkeeping
+				ih.ID = idSeq.NextHashID()
+				ih.DebugOut = headLog(ih.DebugOut, ih.ID, hc.Name)
+				ih.ErrOut = headLog(ih.ErrOut, ih.ID, hc.Name)
+			}
+			if hc.Name != "" {
+				named[hc.Name] = append(named[hc.Name], instances...)
+			}
+			configs = append(configs, configured{hc: hc, instances: instances})
+		}
+
+		// Pipe the output of producers to the stdin of consumers
+		for _, c := range configs {
+			if c.hc.StdInFrom == "" {
+				continue
+			}
+			DebugOut.Printf("HeadC %s StdInFrom: %s\n", c.hc.Command, c.hc.StdInFrom)
+			producers, ok := named[c.hc.StdInFrom]
+			if !ok {
+				ErrorOut.Fatalf("Error piping to '%s': no head named '%s'\n", c.hc.Command, c.hc.StdInFrom)
+			} else if c.hc.StdInFrom == c.hc.Name {
+				ErrorOut.Fatalf("Error piping to '%s': a head can't pipe to itself\n", c.hc.Command)
+			} else if len(c.instances) > 1 {
+				ErrorOut.Fatalf("Error piping to '%s': a head with StdInFrom must have a Number of 1\n", c.hc.Command)
+			}
+
+			pipe, err := c.hc.GetPipe(c.instances[0])
+			if err != nil {
+				ErrorOut.Fatalf("Error piping to '%s': %s\n", c.hc.Command, err)
+			}
+			pipes[c.instances[0]] = pipe
+			for _, p := range producers {
+				p.OutputSinks = append(p.OutputSinks, pipe)
+			}
+		}
+
+		for _, c := range configs {
+			for _, ih := range c.instances {
+				heads.Store(ih.ID, ih)
+				wg.Add(1)
+
+				// Run the head
+				rs := ih.Run()
+				DebugOut.Printf("Conf: %s\n", ih.String())
+				DebugOut.Printf("Live: %s\n", rs)
+
+				// Wait for this head to finish, or not
+				go func(r *head.Head, pipe *head.Pipe) {
+					defer wg.Done()
+					defer heads.Delete(r.ID)
+					if pipe != nil {
+						defer pipe.Close()
+					}
+					r.Wait()
+				}(ih, pipes[ih])
+			}
 		}
 	}
 
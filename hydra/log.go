@@ -1,13 +1,30 @@
 package main
 
 import (
+	"encoding/json"
 	"io"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/cognusion/prochydra/head"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// LogFormats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Log levels, for JSON logs
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelError = "error"
+)
+
 // GetLog gets a standard-type log
 func GetLog(filename, prefix string, format, size, backups, age int) *log.Logger {
 	return getLog(filename, prefix, format, size, backups, age, os.Stdout)
@@ -18,19 +35,130 @@ func GetErrorLog(filename, prefix string, format, size, backups, age int) *log.L
 	return getLog(filename, prefix, format, size, backups, age, os.Stderr)
 }
 
+// GetJSONErrorLog gets an error-type log that writes each message as a JSON object of the level
+func GetJSONErrorLog(filename, level string, size, backups, age int) *log.Logger {
+	return log.New(&JSONWriter{W: getWriter(filename, size, backups, age, os.Stderr), Level: level}, "", 0)
+}
+
 // getLog abstracts all the things
 func getLog(filename, prefix string, format, size, backups, age int, defaultWriter io.Writer) (l *log.Logger) {
+	return log.New(getWriter(filename, size, backups, age, defaultWriter), prefix, format)
+}
+
+// getWriter returns defaultWriter if filename is empty, or a rotating writer to filename
+func getWriter(filename string, size, backups, age int, defaultWriter io.Writer) io.Writer {
 	if filename == "" {
 		// Nothing provided, use the defaults
-		l = log.New(defaultWriter, prefix, format)
-	} else {
-		// File, use lumberjack
-		l = log.New(&lumberjack.Logger{
-			Filename:   filename,
-			MaxSize:    size, // megabytes
-			MaxBackups: backups,
-			MaxAge:     age, // days
-		}, prefix, format)
+		return defaultWriter
+	}
+	// File, use lumberjack
+	return &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    size, // megabytes
+		MaxBackups: backups,
+		MaxAge:     age, // days
+	}
+}
+
+// outputPrefix returns the output prefix template with {headname} replaced by name, leaving
+// the rest for head.ExpandPrefix
+func outputPrefix(prefix, name string) string {
+	return strings.ReplaceAll(prefix, "{headname}", name)
+}
+
+// headLog returns l, or if l is a JSON log, a copy of it that adds the head ID and name
+// to each message
+func headLog(l *log.Logger, id, name string) *log.Logger {
+	jw, ok := l.Writer().(*JSONWriter)
+	if !ok {
+		return l
+	}
+	hw := *jw
+	hw.HeadID = id
+	hw.HeadName = name
+	return log.New(&hw, "", 0)
+}
+
+// jsonOutput moves the child output of the head from its StdOut and StdErr logs to a
+// JSONSink writing where they did
+func jsonOutput(h *head.Head, name string) {
+	h.OutputSinks = append(h.OutputSinks, &JSONSink{Out: h.StdOut.Writer(), Err: h.StdErr.Writer(), HeadName: name})
+	h.StdOut = log.New(io.Discard, "", 0)
+	h.StdErr = log.New(io.Discard, "", 0)
+}
+
+// jsonLine is a line of a JSON log
+type jsonLine struct {
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	HeadID   string    `json:"head_id,omitempty"`
+	HeadName string    `json:"head_name,omitempty"`
+	Instance string    `json:"instance,omitempty"`
+	Stream   string    `json:"stream,omitempty"`
+	Message  string    `json:"message"`
+	Partial  bool      `json:"partial,omitempty"`
+}
+
+// writeJSON writes the jsonLine to w, as one line
+func writeJSON(w io.Writer, jl jsonLine) error {
+	b, err := json.Marshal(jl)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// JSONWriter is an io.Writer for a log.Logger, that writes each message to W as a JSON object
+type JSONWriter struct {
+	// W is where the JSON is written
+	W io.Writer
+	// Level is the level of every message
+	Level string
+	// HeadID is the ID of the head every message is about, if any
+	HeadID string
+	// HeadName is the name of the head every message is about, if any
+	HeadName string
+}
+
+// Write writes the message p as a JSON object
+func (j *JSONWriter) Write(p []byte) (int, error) {
+	err := writeJSON(j.W, jsonLine{
+		Time:     time.Now(),
+		Level:    j.Level,
+		HeadID:   j.HeadID,
+		HeadName: j.HeadName,
+		Message:  strings.TrimSuffix(string(p), "\n"),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// JSONSink is a head.OutputSink that writes each Line as a JSON object, to Out for stdout
+// and Err for stderr
+type JSONSink struct {
+	Out io.Writer
+	Err io.Writer
+	// HeadName is the name of the head, if any
+	HeadName string
+}
+
+// Output writes the Line as a JSON object. stdout is LevelInfo and stderr LevelError.
+func (s *JSONSink) Output(l head.Line) {
+	w, level := s.Out, LevelInfo
+	if l.Stream == head.StreamStderr {
+		w, level = s.Err, LevelError
 	}
-	return
+	writeJSON(w, jsonLine{
+		Time:     l.Time,
+		Level:    level,
+		HeadID:   l.HeadID,
+		HeadName: s.HeadName,
+		Instance: l.Name,
+		Stream:   string(l.Stream),
+		Message:  string(l.Bytes),
+		Partial:  l.Partial,
+	})
 }
@@ -3,12 +3,15 @@ package main
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"syscall"
 
 	sq "github.com/Hellseher/go-shellquote"
 	"github.com/cognusion/go-recyclable"
 	"github.com/cognusion/prochydra/greek"
 	"github.com/cognusion/prochydra/head"
+	"golang.org/x/sys/unix"
 )
 
 var (
@@ -98,14 +101,174 @@ func handleRequest(req *greek.Request) {
 				}
 			}
 			return
-		case greek.Send, greek.Connect:
+		case greek.Pause, greek.Resume:
+			// Pause or Resume specific Head
+			var (
+				err  error
+				done = "Head Paused\n"
+			)
+			if req.Verb == greek.Pause {
+				err = h.Pause()
+			} else {
+				err = h.Resume()
+				done = "Head Resumed\n"
+			}
+			if req.Waiting {
+				if err != nil {
+					buf.Close()
+					req.Chan <- greek.Response{
+						IsFinal: true,
+						Error:   fmt.Errorf("cannot %s head: %w", req.Verb, err),
+					}
+					return
+				}
+				io.WriteString(buf, done)
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Reset:
+			// Reset the breaker of specific Head
+			if err := h.Reset(); err != nil {
+				if req.Waiting {
+					buf.Close()
+					req.Chan <- greek.Response{
+						IsFinal: true,
+						Error:   fmt.Errorf("cannot reset head: %w", err),
+					}
+				}
+				return
+			}
+			if req.Waiting {
+				io.WriteString(buf, "Head Reset\n")
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Signal:
+			// Signal specific Head
+			var sig syscall.Signal
+			if len(rd) < 2 {
+				err = fmt.Errorf("no signal specified")
+			} else if sig, err = head.ParseSignal(rd[1]); err == nil {
+				err = h.Signal(sig)
+			}
+			if req.Waiting {
+				if err != nil {
+					buf.Close()
+					req.Chan <- greek.Response{
+						IsFinal: true,
+						Error:   fmt.Errorf("cannot signal head: %w", err),
+					}
+					return
+				}
+				io.WriteString(buf, fmt.Sprintf("Head Signalled (%s)\n", unix.SignalName(sig)))
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Health:
+			// Health of specific Head
+			if req.Waiting {
+				hh := CheckHead(h)
+				WriteHealth(buf, hh.Health, []HeadHealth{hh})
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Tail:
+			// Recent output of specific Head: [n] [stdout|stderr]
+			var (
+				n      = 10
+				stream head.Stream
+			)
+			for _, arg := range rd[1:] {
+				if i, aerr := strconv.Atoi(arg); aerr == nil && i >= 0 {
+					n = i
+				} else if stream, err = head.ToStream(arg); err != nil {
+					break
+				}
+			}
+			if req.Waiting {
+				if err != nil {
+					buf.Close()
+					req.Chan <- greek.Response{
+						IsFinal: true,
+						Error:   fmt.Errorf("cannot tail head: %w", err),
+					}
+					return
+				}
+				for _, l := range h.Tail(n, stream) {
+					io.WriteString(buf, l.String()+"\n")
+				}
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.History:
+			// History of specific Head
+			if req.Waiting {
+				for _, rr := range h.History() {
+					io.WriteString(buf, rr.String()+"\n")
+				}
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Events:
+			// Recent events of specific Head
+			if req.Waiting {
+				for _, e := range recentEvents.Events(h.ID) {
+					io.WriteString(buf, e.String()+"\n")
+				}
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Connect:
+			// Interactive session with specific Head, until the client detaches
+			if req.Input == nil || !req.Waiting {
+				if req.Waiting {
+					buf.Close()
+					req.Chan <- greek.Response{
+						IsFinal: true,
+						Error:   fmt.Errorf("cannot connect to head: not a session"),
+					}
+				}
+				return
+			}
+			if data != "" && !h.StdInNoNL {
+				// Send what came with the request first, as Send would.
+				data += "\n"
+			}
+			buf.Close()
+			req.Chan <- greek.Response{
+				IsFinal: true,
+				Data:    newSession(h, []byte(data), req.Input),
+				Stream:  true,
+			}
+			return
+		case greek.Send:
 			// Send to specific Head
 			if !h.StdInNoNL {
 				// Append NL unless explicitly told not to.
 				data += "\n"
 			}
 			io.WriteString(h, data)
-			// TODO: grab stdout?
 			if req.Waiting {
 				io.WriteString(buf, "Head Written\n")
 				req.Chan <- greek.Response{
@@ -135,13 +298,127 @@ func handleRequest(req *greek.Request) {
 				}
 			}
 			return
+		case greek.Pause, greek.Resume:
+			// Pause or Resume Heads, reporting each
+			heads.Range(func(k, v interface{}) bool {
+				h := v.(*head.Head)
+				if h == nil {
+					return true
+				}
+				var err error
+				if req.Verb == greek.Pause {
+					err = h.Pause()
+				} else {
+					err = h.Resume()
+				}
+				if req.Waiting {
+					if err != nil {
+						io.WriteString(buf, fmt.Sprintf("%s: %s\n", h.ID, err))
+					} else {
+						io.WriteString(buf, fmt.Sprintf("%s: %sd\n", h.ID, req.Verb))
+					}
+				}
+				return true
+			})
+			if req.Waiting {
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Reset:
+			// Reset the breakers of tripped Heads, reporting each
+			heads.Range(func(k, v interface{}) bool {
+				h := v.(*head.Head)
+				if h != nil && h.Tripped() {
+					err := h.Reset()
+					if req.Waiting {
+						if err != nil {
+							io.WriteString(buf, fmt.Sprintf("%s: %s\n", h.ID, err))
+						} else {
+							io.WriteString(buf, fmt.Sprintf("%s: reset\n", h.ID))
+						}
+					}
+				}
+				return true
+			})
+			if req.Waiting {
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Signal:
+			// Signal Heads, reporting each
+			sig, err := head.ParseSignal(req.Data)
+			if err != nil {
+				if req.Waiting {
+					buf.Close()
+					req.Chan <- greek.Response{
+						IsFinal: true,
+						Error:   fmt.Errorf("cannot signal heads: %w", err),
+					}
+				}
+				return
+			}
+			heads.Range(func(k, v interface{}) bool {
+				h := v.(*head.Head)
+				if h == nil {
+					return true
+				}
+				err := h.Signal(sig)
+				if req.Waiting {
+					if err != nil {
+						io.WriteString(buf, fmt.Sprintf("%s: %s\n", h.ID, err))
+					} else {
+						io.WriteString(buf, fmt.Sprintf("%s: signalled (%s)\n", h.ID, unix.SignalName(sig)))
+					}
+				}
+				return true
+			})
+			if req.Waiting {
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Health:
+			// Health of all Heads
+			if req.Waiting {
+				overall, hhs := CheckHeads()
+				WriteHealth(buf, overall, hhs)
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
+		case greek.Events:
+			// Recent events of all Heads
+			if req.Waiting {
+				for _, e := range recentEvents.Events("") {
+					io.WriteString(buf, e.String()+"\n")
+				}
+				req.Chan <- greek.Response{
+					IsFinal: true,
+					Data:    buf,
+				}
+			}
+			return
 		case greek.List:
 			// LIST HEADS
 			if req.Waiting {
 				heads.Range(func(k, v interface{}) bool {
 					h := v.(*head.Head)
 					if h != nil {
-						io.WriteString(buf, fmt.Sprintf("%s: %s - %d\n", h.ID, h.String(), h.Restarts()))
+						io.WriteString(buf, fmt.Sprintf("%s: %s - %d - %s", h.ID, h.String(), h.Restarts(), h.Instance()))
+						if h.Tripped() {
+							io.WriteString(buf, " breaker=tripped")
+						}
+						io.WriteString(buf, "\n")
 					}
 					return true
 				})
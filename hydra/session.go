@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cognusion/prochydra/head"
+)
+
+// sessionBuffer is the number of lines of output a session buffers for a slow client,
+// before dropping them rather than hold up the head
+const sessionBuffer = 1000
+
+// session is an interactive connection to a head: what is read from the client's input is
+// written to the head's stdin, and the head's stdout and stderr can be Read, until the input
+// ends, the head's process does, or the session is Closed
+type session struct {
+	h      *head.Head
+	lines  chan []byte
+	events chan head.Event
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+	detach func()
+	once   sync.Once
+	done   chan struct{}
+}
+
+// newSession attaches a session to the head, writing first, if any, to its stdin, and then
+// the client's input read from in
+func newSession(h *head.Head, first []byte, in io.Reader) *session {
+	pr, pw := io.Pipe()
+	s := &session{
+		h:      h,
+		lines:  make(chan []byte, sessionBuffer),
+		events: make(chan head.Event, 10),
+		pr:     pr,
+		pw:     pw,
+		done:   make(chan struct{}),
+	}
+	i := h.Instance()
+	switch i.State {
+	case head.StateBackoff, head.StateFailed:
+		s.send([]byte(fmt.Sprintf("Connected to head %s (%s). No process is running to take input. Close input to detach.\n", h.ID, i)))
+	default:
+		s.send([]byte(fmt.Sprintf("Connected to head %s (%s). Close input to detach.\n", h.ID, i)))
+	}
+	s.detach = h.Attach(head.OutputFunc(func(l head.Line) {
+		if l.Partial {
+			s.send(l.Bytes)
+		} else {
+			s.send(append(l.Bytes[:len(l.Bytes):len(l.Bytes)], '\n'))
+		}
+	}))
+	h.Subscribe(s.events)
+	if h.State() == head.StateStopped {
+		// It's done, and on its way out
+		s.send([]byte(fmt.Sprintf("hydra: head %s has stopped\n", h.ID)))
+		s.end()
+	} else if len(first) > 0 {
+		s.write(first)
+	}
+
+	go s.output()
+	go s.input(in)
+	go s.watch()
+	return s
+}
+
+// watch ends the session when the head's process does, as its stdin goes with it
+func (s *session) watch() {
+	for {
+		select {
+		case e := <-s.events:
+			if e.Ended() {
+				s.send([]byte(fmt.Sprintf("hydra: head %s %s\n", s.h.ID, e.Type)))
+				s.end()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// send queues b for the client, dropping it if the client is too far behind
+func (s *session) send(b []byte) {
+	select {
+	case s.lines <- b:
+	default:
+	}
+}
+
+// output writes queued lines to the client, until the session is Closed
+func (s *session) output() {
+	for {
+		select {
+		case b := <-s.lines:
+			if _, err := s.pw.Write(b); err != nil {
+				s.end()
+				return
+			}
+		case <-s.done:
+			// Flush what's queued
+			for {
+				select {
+				case b := <-s.lines:
+					if _, err := s.pw.Write(b); err != nil {
+						return
+					}
+				default:
+					s.pw.Close()
+					return
+				}
+			}
+		}
+	}
+}
+
+// input writes what the client sends to the head's stdin, as it comes, until the client
+// detaches
+func (s *session) input(in io.Reader) {
+	defer s.end()
+
+	b := make([]byte, 4096)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			s.write(b[:n])
+		}
+		if err != nil {
+			return
+		}
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+	}
+}
+
+// write writes b to the head's stdin, telling the client if it can't
+func (s *session) write(b []byte) {
+	if _, err := s.h.Write(b); err != nil {
+		s.send([]byte(fmt.Sprintf("hydra: cannot write to head: %s\n", err)))
+	}
+}
+
+// Read reads the head's output
+func (s *session) Read(p []byte) (int, error) {
+	return s.pr.Read(p)
+}
+
+// Close detaches the session from the head, and stops Reads
+func (s *session) Close() error {
+	s.end()
+	return s.pr.Close()
+}
+
+// end detaches the session from the head, and ends Reads once queued output is read
+func (s *session) end() {
+	s.once.Do(func() {
+		s.detach()
+		s.h.Unsubscribe(s.events)
+		close(s.done)
+	})
+}
@@ -1,6 +1,7 @@
 package lerna
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -69,7 +70,8 @@ func handleConnection(conn net.Conn, requestChan chan<- greek.Request, outLog *l
 	defer buf.Close()
 	buf.Reset([]byte{})
 	outLog.Println("server Reading...")
-	_, err := io.Copy(buf, conn)
+	in := bufio.NewReader(conn)
+	session, err := readRequest(in, buf)
 	if err != nil {
 		outLog.Println(err)
 		return
@@ -92,13 +94,17 @@ func handleConnection(conn net.Conn, requestChan chan<- greek.Request, outLog *l
 	args[1] = strings.ToLower(args[1])
 
 	respChan := make(chan greek.Response)
-	requestChan <- greek.Request{
+	req := greek.Request{
 		Verb:    greek.ToVerb(args[0]),
 		Noun:    greek.ToNoun(args[1]),
 		Data:    sq.Join(args[2:]...),
 		Waiting: true,
 		Chan:    respChan,
 	}
+	if session {
+		req.Input = in
+	}
+	requestChan <- req
 
 	outLog.Println("server Waiting for response from control...")
 	resp := <-respChan
@@ -112,6 +118,16 @@ func handleConnection(conn net.Conn, requestChan chan<- greek.Request, outLog *l
 	}
 	defer resp.Data.Close() // make sure that gets closed
 
+	if resp.Stream {
+		outLog.Println("server Streaming...")
+		_, err = io.Copy(conn, resp.Data)
+		if err != nil {
+			outLog.Println(err)
+		}
+		outLog.Println("<<< stream ended")
+		return
+	}
+
 	// Reuse our existing buffer
 	buf.ResetFromReader(resp.Data)
 
@@ -126,6 +142,25 @@ func handleConnection(conn net.Conn, requestChan chan<- greek.Request, outLog *l
 	outLog.Println("<<< ", buf.String())
 }
 
+// readRequest reads a request from the reader into buf: everything until EOF, or for
+// session Verbs, the first line, leaving the rest for the session, and returning true.
+func readRequest(in *bufio.Reader, buf io.Writer) (bool, error) {
+	line, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	io.WriteString(buf, line)
+	if err == io.EOF {
+		return false, nil
+	}
+
+	if verb, _, _ := strings.Cut(strings.TrimSpace(line), " "); greek.IsSession(greek.ToVerb(strings.ToLower(verb))) {
+		return true, nil
+	}
+	_, err = io.Copy(buf, in)
+	return false, err
+}
+
 func socketCleanup(socketAddress string) error {
 	if _, serr := os.Stat(socketAddress); serr == nil {
 		if rerr := os.RemoveAll(socketAddress); rerr != nil {